@@ -0,0 +1,205 @@
+// Command checkout-stress menembak POST /api/checkout secara paralel ke server
+// yang terhubung ke Postgres lokal, lalu memastikan stok tidak oversell/negatif
+// dan tidak ada request yang gagal karena deadlock.
+//
+// Contoh:
+//
+//	go run ./cmd/checkout-stress -url http://localhost:3000 -token $ACCESS_TOKEN -products 1,2,3 -workers 20 -requests 500
+//
+// Access token didapat dari POST /api/auth/login.
+//
+// Locking di repository sendiri diuji oleh TestCreateTransactionConcurrentCheckout
+// (go test ./repositories dengan KASIR_TEST_DB_CONN); tool ini untuk uji beban end-to-end lewat HTTP.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kasir-api/models"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:3000", "Base URL server kasir-api")
+	productsFlag := flag.String("products", "1,2", "Daftar product ID yang dipakai, dipisah koma")
+	workers := flag.Int("workers", 20, "Jumlah goroutine paralel")
+	requests := flag.Int("requests", 200, "Total request checkout")
+	quantity := flag.Int("qty", 1, "Quantity per item")
+	token := flag.String("token", "", "Access token dari POST /api/auth/login")
+	flag.Parse()
+
+	productIDs, err := parseIDs(*productsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: bearerTransport{token: *token, next: http.DefaultTransport},
+	}
+
+	stockBefore := make(map[int]int)
+	for _, id := range productIDs {
+		stock, err := getStock(client, *baseURL, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stockBefore[id] = stock
+	}
+
+	var mu sync.Mutex
+	statusCount := make(map[int]int)
+	sold := make(map[int]int)
+	serverErrors := make([]string, 0)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for range jobs {
+				items := randomCart(rng, productIDs, *quantity)
+				status, body, err := checkout(client, *baseURL, items)
+				mu.Lock()
+				if err != nil {
+					serverErrors = append(serverErrors, err.Error())
+				} else {
+					statusCount[status]++
+					if status == http.StatusOK {
+						for _, item := range items {
+							sold[item.ProductID] += item.Quantity
+						}
+					} else if status >= http.StatusInternalServerError {
+						serverErrors = append(serverErrors, body)
+					}
+				}
+				mu.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+
+	start := time.Now()
+	for i := 0; i < *requests; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Printf("%d request selesai dalam %s\n", *requests, elapsed)
+	for status, count := range statusCount {
+		fmt.Printf("  HTTP %d: %d\n", status, count)
+	}
+
+	failed := false
+	for _, id := range productIDs {
+		stockAfter, err := getStock(client, *baseURL, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		expected := stockBefore[id] - sold[id]
+		fmt.Printf("  produk %d: stok awal %d, terjual %d, stok akhir %d (expected %d)\n",
+			id, stockBefore[id], sold[id], stockAfter, expected)
+		if stockAfter != expected || stockAfter < 0 {
+			failed = true
+		}
+	}
+
+	if len(serverErrors) > 0 {
+		fmt.Printf("%d request gagal dengan error server, contoh: %s\n", len(serverErrors), strings.TrimSpace(serverErrors[0]))
+		failed = true
+	}
+
+	if failed {
+		fmt.Println("GAGAL: stok tidak konsisten atau ada error server")
+		os.Exit(1)
+	}
+	fmt.Println("OK: stok konsisten")
+}
+
+// bearerTransport - tambahkan header Authorization ke setiap request
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+func parseIDs(value string) ([]int, error) {
+	ids := make([]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("product id tidak valid: %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// randomCart - keranjang dengan urutan produk acak dan kadang product_id duplikat,
+// supaya lock ordering dan penggabungan item ikut teruji
+func randomCart(rng *rand.Rand, productIDs []int, quantity int) []models.CheckoutItem {
+	items := make([]models.CheckoutItem, 0)
+	for _, i := range rng.Perm(len(productIDs)) {
+		items = append(items, models.CheckoutItem{ProductID: productIDs[i], Quantity: quantity})
+		if rng.Intn(4) == 0 {
+			items = append(items, models.CheckoutItem{ProductID: productIDs[i], Quantity: quantity})
+		}
+	}
+	return items
+}
+
+func checkout(client *http.Client, baseURL string, items []models.CheckoutItem) (int, string, error) {
+	payload, err := json.Marshal(models.CheckoutRequest{Items: items})
+	if err != nil {
+		return 0, "", err
+	}
+
+	resp, err := client.Post(baseURL+"/api/checkout", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	return resp.StatusCode, body.String(), nil
+}
+
+func getStock(client *http.Client, baseURL string, productID int) (int, error) {
+	resp, err := client.Get(fmt.Sprintf("%s/api/produk/%d", baseURL, productID))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("gagal ambil produk %d: HTTP %d", productID, resp.StatusCode)
+	}
+
+	var product models.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		return 0, err
+	}
+	return product.Stock, nil
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"net/http"
+	"strings"
+)
+
+type AuthHandler struct {
+	service *services.AuthService
+}
+
+func NewAuthHandler(service *services.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+type authContextKey struct{}
+
+type overrideContextKey struct{}
+
+// Permissions - izin yang dibutuhkan per HTTP method untuk satu route
+type Permissions map[string]models.Permission
+
+// CurrentUser - user yang login untuk request ini, nil jika route tidak melewati middleware auth
+func CurrentUser(r *http.Request) *models.AuthUser {
+	user, _ := r.Context().Value(authContextKey{}).(*models.AuthUser)
+	return user
+}
+
+// CurrentOverride - override supervisor yang dikirim bersama request, nil jika tidak ada
+func CurrentOverride(r *http.Request) *models.SupervisorOverride {
+	override, _ := r.Context().Value(overrideContextKey{}).(*models.SupervisorOverride)
+	return override
+}
+
+// Allowed - role user punya izin, atau supervisor sudah menyetujui izin tersebut lewat override.
+// Override hanya dicek izinnya di sini; kasir peminta dicek saat verifikasi token, sedangkan transaksi
+// target dan status sekali pakai dicek saat override ditandai terpakai bersama aksinya.
+func Allowed(r *http.Request, perm models.Permission) bool {
+	if user := CurrentUser(r); user != nil && models.HasPermission(user.Role, perm) {
+		return true
+	}
+	override := CurrentOverride(r)
+	return override != nil && override.Permission == perm
+}
+
+// CurrentActor - user login, terminal dari header X-Terminal-ID, dan override supervisor (jika ada)
+// untuk dicatat pada transaksi/refund. Override hanya dipakai (dan dihabiskan) jika user tidak
+// punya izin tersebut sendiri.
+func CurrentActor(r *http.Request) models.Actor {
+	actor := models.Actor{TerminalID: strings.TrimSpace(r.Header.Get("X-Terminal-ID"))}
+	user := CurrentUser(r)
+	if user != nil {
+		actor.UserID = user.UserID
+	}
+	if override := CurrentOverride(r); override != nil && (user == nil || !models.HasPermission(user.Role, override.Permission)) {
+		actor.ApprovedBy = override.ApproverID
+		actor.OverrideID = override.ID
+		actor.OverridePermission = override.Permission
+	}
+	return actor
+}
+
+// forbidden - respons 403 yang sama untuk semua penolakan izin
+func forbidden(w http.ResponseWriter, perm models.Permission) {
+	http.Error(w, fmt.Sprintf("Forbidden: butuh izin %s", perm), http.StatusForbidden)
+}
+
+// Authorize - cek izin sesuai method sebelum meneruskan ke handler; method yang tidak
+// terdaftar ditolak dengan 405
+func Authorize(perms Permissions, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		perm, ok := perms[r.Method]
+		if !ok {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !Allowed(r, perm) {
+			forbidden(w, perm)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// publicPaths - path /api yang boleh diakses tanpa login
+var publicPaths = map[string]bool{
+	"/api/auth/login":   true,
+	"/api/auth/refresh": true,
+}
+
+// Middleware - wajibkan Bearer token yang valid untuk semua route /api kecuali publicPaths
+func (h *AuthHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Authorization Bearer token wajib diisi", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := h.service.Authenticate(strings.TrimSpace(token))
+		if errors.Is(err, services.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authContextKey{}, user)
+
+		if overrideToken := r.Header.Get("X-Supervisor-Token"); overrideToken != "" {
+			override, err := h.service.VerifyOverride(overrideToken, user.UserID)
+			if errors.Is(err, services.ErrInvalidOverride) {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			ctx = context.WithValue(ctx, overrideContextKey{}, override)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// HandleLogin - POST /api/auth/login
+func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Login(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Login godoc
+// @Summary Login
+// @Description Login dengan username dan password, mengembalikan access token (JWT) dan refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Username dan password"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.service.Login(req)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// HandleRefresh - POST /api/auth/refresh
+func (h *AuthHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Refresh(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Refresh godoc
+// @Summary Refresh token
+// @Description Menukar refresh token dengan access token dan refresh token baru; refresh token lama tidak bisa dipakai lagi
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.service.Refresh(req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// HandleLogout - POST /api/auth/logout
+func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Logout(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Mengakhiri sesi login; access token dan refresh token sesi ini tidak bisa dipakai lagi
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Logout(CurrentUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// HandleOverride - POST /api/auth/override
+func (h *AuthHandler) HandleOverride(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Override(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Override godoc
+// @Summary Supervisor override
+// @Description Supervisor/owner memasukkan kredensialnya di terminal kasir untuk menyetujui satu aksi kasir: transaction:void, transaction:refund (wajib isi transaction_id) atau transaction:price_override. Token yang dihasilkan dikirim kasir yang login lewat header X-Supervisor-Token, hanya berlaku untuk kasir dan transaksi tersebut, sekali pakai, dan kedaluwarsa dalam 2 menit.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OverrideRequest true "Kredensial supervisor dan izin yang disetujui"
+// @Success 200 {object} models.OverrideResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/auth/override [post]
+func (h *AuthHandler) Override(w http.ResponseWriter, r *http.Request) {
+	var req models.OverrideRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	override, err := h.service.IssueOverride(req, CurrentUser(r).UserID)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if errors.Is(err, repositories.ErrTransactionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(override)
+}
+
+// HandleMe - GET /api/auth/me
+func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Me(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Me godoc
+// @Summary Current user
+// @Description Mengambil data user yang sedang login
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/me [get]
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUser(CurrentUser(r).UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"net/http"
 	"strconv"
@@ -31,14 +33,27 @@ func (h *CategoryHandler) HandleCategories(w http.ResponseWriter, r *http.Reques
 
 // GetAll godoc
 // @Summary Get all categories
-// @Description Mengambil semua daftar kategori
+// @Description Mengambil semua daftar kategori aktif; archived=true untuk daftar kategori yang diarsipkan
 // @Tags Categories
 // @Accept json
 // @Produce json
+// @Param archived query bool false "List archived categories instead of active ones"
 // @Success 200 {array} models.Category
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/kategori [get]
 func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	categories, err := h.service.GetAll()
+	archived := false
+	if value := r.URL.Query().Get("archived"); value != "" {
+		var err error
+		archived, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid archived", http.StatusBadRequest)
+			return
+		}
+	}
+
+	categories, err := h.service.GetAll(archived)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -57,6 +72,7 @@ func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 // @Param category body models.Category true "Category data"
 // @Success 201 {object} models.Category
 // @Failure 400 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/kategori [post]
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var category models.Category
@@ -77,13 +93,31 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(category)
 }
 
-// HandleCategoryByID - GET/PUT/DELETE /api/kategori/{id}
+// writeCategoryError - versi tidak cocok 412, kategori tidak ada 404, status arsip tidak sesuai atau
+// masih dipakai 409, selain itu 400
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, repositories.ErrCategoryInUse), errors.Is(err, repositories.ErrCategoryArchived),
+		errors.Is(err, repositories.ErrCategoryNotArchived):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrCategoryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// HandleCategoryByID - GET/PUT/PATCH/DELETE /api/kategori/{id}
 func (h *CategoryHandler) HandleCategoryByID(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		h.GetByID(w, r)
 	case http.MethodPut:
 		h.Update(w, r)
+	case http.MethodPatch:
+		h.Patch(w, r)
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
@@ -99,8 +133,10 @@ func (h *CategoryHandler) HandleCategoryByID(w http.ResponseWriter, r *http.Requ
 // @Produce json
 // @Param id path int true "Category ID"
 // @Success 200 {object} models.Category
+// @Header 200 {string} ETag "Versi kategori, kirim kembali di If-Match saat PUT/PATCH"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/kategori/{id} [get]
 func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/kategori/")
@@ -116,21 +152,26 @@ func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, category.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
 }
 
 // Update godoc
 // @Summary Update category
-// @Description Mengedit kategori berdasarkan ID
+// @Description Mengedit kategori berdasarkan ID (semua field diganti). Kirim version (atau header If-Match) untuk menolak edit jika kategori sudah diubah orang lain.
 // @Tags Categories
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param If-Match header string false "ETag / version kategori yang diedit"
 // @Param category body models.Category true "Category data"
 // @Success 200 {object} models.Category
+// @Header 200 {string} ETag "Versi kategori yang baru"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/kategori/{id} [put]
 func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/kategori/")
@@ -146,28 +187,91 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if version > 0 {
+		category.Version = version
+	}
 
 	category.ID = id
 	err = h.service.Update(&category)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	setETag(w, category.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// Patch godoc
+// @Summary Partially update category
+// @Description Mengubah sebagian field kategori; field yang tidak dikirim tidak diubah. tax_rate null = kembali ikut tarif default. Kirim version (atau header If-Match) untuk menolak edit jika kategori sudah diubah orang lain.
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param If-Match header string false "ETag / version kategori yang diedit"
+// @Param category body models.CategoryPatch true "Fields to update"
+// @Success 200 {object} models.Category
+// @Header 200 {string} ETag "Versi kategori yang baru"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/kategori/{id} [patch]
+func (h *CategoryHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/kategori/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var patch models.CategoryPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	version, err := ifMatchVersion(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if version > 0 {
+		patch.Version = version
+	}
 
+	category, err := h.service.Patch(id, patch)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	setETag(w, category.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
 }
 
 // Delete godoc
-// @Summary Delete category
-// @Description Menghapus kategori berdasarkan ID
+// @Summary Archive or delete category
+// @Description Mengarsipkan kategori (default): kategori disembunyikan dari daftar tetapi produknya tetap memakai kategori ini. Dengan permanent=true kategori dihapus permanen, hanya jika tidak dipakai produk (termasuk yang diarsipkan) maupun promo.
 // @Tags Categories
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param permanent query bool false "Hard delete (only for unused categories)"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/kategori/{id} [delete]
 func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/kategori/")
@@ -177,14 +281,63 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Delete(id)
+	permanent := false
+	if value := r.URL.Query().Get("permanent"); value != "" {
+		permanent, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid permanent", http.StatusBadRequest)
+			return
+		}
+	}
+
+	message := "Category archived successfully"
+	if permanent {
+		message = "Category deleted successfully"
+		err = h.service.Delete(id)
+	} else {
+		err = h.service.Archive(id)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeCategoryError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Category deleted successfully",
+		"message": message,
 	})
 }
+
+// Restore godoc
+// @Summary Restore archived category
+// @Description Memulihkan kategori yang diarsipkan
+// @Tags Categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/kategori/{id}/pulihkan [post]
+func (h *CategoryHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Restore(id); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	category, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setETag(w, category.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+)
+
+type NotificationHandler struct {
+	service *services.NotificationService
+}
+
+func NewNotificationHandler(service *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// GetAll godoc
+// @Summary Get notifications
+// @Description Mengambil notifikasi in-app terbaru (mis. stok menipis)
+// @Tags Notifications
+// @Produce json
+// @Param unread query bool false "Hanya yang belum dibaca"
+// @Param limit query int false "Jumlah data (default 50, maks 200)"
+// @Success 200 {array} models.Notification
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/notifikasi [get]
+func (h *NotificationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.NotificationFilter
+
+	if v := query.Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid unread", http.StatusBadRequest)
+			return
+		}
+		filter.UnreadOnly = unread
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	notifications, err := h.service.GetAll(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// MarkRead godoc
+// @Summary Mark notification as read
+// @Description Menandai notifikasi sudah dibaca
+// @Tags Notifications
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/notifikasi/{id}/dibaca [post]
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.MarkRead(id)
+	if errors.Is(err, repositories.ErrNotificationNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Notification marked as read",
+	})
+}
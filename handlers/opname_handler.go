@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+)
+
+type OpnameHandler struct {
+	service *services.OpnameService
+}
+
+func NewOpnameHandler(service *services.OpnameService) *OpnameHandler {
+	return &OpnameHandler{service: service}
+}
+
+// writeOpnameError - sesi tidak ada 404, sesi sudah final/batal 409, selain itu 400
+func writeOpnameError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrOpnameNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrOpnameNotOpen):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// HandleSessions - GET/POST /api/opname
+func (h *OpnameHandler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetAll godoc
+// @Summary Get stock opname sessions
+// @Description Mengambil semua sesi stock opname
+// @Tags Stock Opname
+// @Produce json
+// @Success 200 {array} models.OpnameSession
+// @Security BearerAuth
+// @Router /api/opname [get]
+func (h *OpnameHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.service.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// Create godoc
+// @Summary Create stock opname session
+// @Description Membuat sesi stock opname baru
+// @Tags Stock Opname
+// @Accept json
+// @Produce json
+// @Param request body models.CreateOpnameRequest true "Session data"
+// @Success 201 {object} models.OpnameSession
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname [post]
+func (h *OpnameHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOpnameRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.service.Create(req, CurrentUser(r).UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// GetByID godoc
+// @Summary Get stock opname session
+// @Description Mengambil sesi stock opname berdasarkan ID
+// @Tags Stock Opname
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} models.OpnameSession
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname/{id} [get]
+func (h *OpnameHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.service.GetByID(id)
+	if err != nil {
+		writeOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// SubmitCounts godoc
+// @Summary Submit counted quantities
+// @Description Mengirim hasil hitung fisik per produk. Kiriman ulang dari perangkat yang sama menimpa hitungan sebelumnya; hitungan dari perangkat berbeda dijumlahkan. Produk induk yang punya varian (hitung per varian) dan produk yang diarsipkan ditolak.
+// @Tags Stock Opname
+// @Accept json
+// @Produce json
+// @Param id path int true "Session ID"
+// @Param X-Terminal-ID header string false "ID perangkat penghitung"
+// @Param request body models.OpnameCountRequest true "Counted quantities"
+// @Success 200 {array} models.OpnameCount
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname/{id}/hitung [post]
+func (h *OpnameHandler) SubmitCounts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.OpnameCountRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := h.service.SubmitCounts(id, req, CurrentActor(r))
+	if err != nil {
+		writeOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
+
+// GetVariance godoc
+// @Summary Get stock opname variance report
+// @Description Mengambil selisih hitung fisik vs stok sistem saat produk dihitung, per produk, beserta nilai kurang/lebih berdasarkan harga produk
+// @Tags Stock Opname
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} models.OpnameVarianceReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname/{id}/selisih [get]
+func (h *OpnameHandler) GetVariance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetVarianceReport(id)
+	if err != nil {
+		writeOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Finalize godoc
+// @Summary Finalize stock opname
+// @Description Menyesuaikan stok produk yang dihitung sebesar selisih hitung fisik vs stok sistem saat produk dihitung (dicatat sebagai mutasi opname di ledger stok), sehingga penjualan setelah hitung tidak hilang, lalu mengunci sesi
+// @Tags Stock Opname
+// @Accept json
+// @Produce json
+// @Param id path int true "Session ID"
+// @Param request body models.FinalizeOpnameRequest true "Alasan penyesuaian"
+// @Success 200 {object} models.OpnameVarianceReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname/{id}/finalisasi [post]
+func (h *OpnameHandler) Finalize(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.FinalizeOpnameRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.Finalize(id, req, CurrentUser(r).UserID)
+	if err != nil {
+		writeOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Cancel godoc
+// @Summary Cancel stock opname
+// @Description Membatalkan sesi stock opname yang masih open tanpa mengubah stok
+// @Tags Stock Opname
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/opname/{id}/batal [post]
+func (h *OpnameHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Cancel(id); err != nil {
+		writeOpnameError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Stock opname cancelled successfully",
+	})
+}
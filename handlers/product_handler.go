@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ProductHandler struct {
@@ -31,16 +34,123 @@ func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request)
 
 // GetAll godoc
 // @Summary Get all products
-// @Description Mengambil semua daftar produk, bisa filter by name
+// @Description Mengambil daftar produk dengan pagination, bisa filter by name, kategori, rentang harga, stok tersedia, dan stok menipis
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param name query string false "Filter by product name"
-// @Success 200 {array} models.Product
+// @Param category_id query int false "Filter by category ID"
+// @Param min_price query int false "Minimum price"
+// @Param max_price query int false "Maximum price"
+// @Param in_stock query bool false "Only products with stock > 0"
+// @Param low_stock query bool false "Only products at or below min_stock"
+// @Param archived query bool false "List archived products instead of active ones"
+// @Param sort query string false "Sort by: name, price, stock, created_at (default name)"
+// @Param order query string false "Sort order: asc, desc (default asc)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} models.ProductListResponse
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/produk [get]
 func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-	products, err := h.service.GetAll(name)
+	q := r.URL.Query()
+	filter := models.ProductFilter{
+		Name:  q.Get("name"),
+		Sort:  q.Get("sort"),
+		Order: strings.ToLower(q.Get("order")),
+	}
+
+	intParams := map[string]*int{
+		"category_id": &filter.CategoryID,
+		"min_price":   &filter.MinPrice,
+		"max_price":   &filter.MaxPrice,
+		"limit":       &filter.Limit,
+		"offset":      &filter.Offset,
+	}
+	for name, dest := range intParams {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dest = n
+	}
+
+	boolParams := map[string]*bool{
+		"in_stock":  &filter.InStock,
+		"low_stock": &filter.LowStock,
+		"archived":  &filter.Archived,
+	}
+	for name, dest := range boolParams {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dest = b
+	}
+
+	result, err := h.service.GetAll(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Search godoc
+// @Summary Search products
+// @Description Pencarian produk untuk type-ahead: cocokkan nama (toleran typo), SKU, barcode, dan nama kategori, terurut relevansi
+// @Tags Products
+// @Produce json
+// @Param q query string true "Search query (min 2 characters)"
+// @Param limit query int false "Max results (default 10, max 50)"
+// @Success 200 {array} models.Product
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/cari [get]
+func (h *ProductHandler) Search(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	products, err := h.service.Search(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+// GetLowStock godoc
+// @Summary Get low-stock products
+// @Description Mengambil produk dengan stok di bawah atau sama dengan min_stock (produk dengan min_stock 0 tidak dipantau), paling kritis lebih dulu
+// @Tags Products
+// @Produce json
+// @Success 200 {array} models.Product
+// @Security BearerAuth
+// @Router /api/produk/stok-menipis [get]
+func (h *ProductHandler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	products, err := h.service.GetLowStock()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -52,13 +162,15 @@ func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 // Create godoc
 // @Summary Add new product
-// @Description Menambahkan produk baru
+// @Description Menambahkan produk baru. Isi parent_id dan variant_name untuk menambah varian (ukuran, warna, rasa) ke produk induk; nama dan kategori varian mengikuti induk.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param product body models.Product true "Product data"
 // @Success 201 {object} models.Product
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/produk [post]
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var product models.Product
@@ -68,9 +180,9 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Create(&product)
+	err = h.service.Create(&product, CurrentUser(r).UserID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProductWriteError(w, err)
 		return
 	}
 
@@ -79,13 +191,37 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
-// HandleProductByID - GET/PUT/DELETE /api/produk/{id}
+// writeProductWriteError - sku/barcode/nama varian bentrok dengan produk lain atau produk diarsipkan 409,
+// produk tidak ada 404, versi tidak cocok 412, selain itu 400
+func writeProductWriteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, repositories.ErrDuplicateSKU), errors.Is(err, repositories.ErrDuplicateBarcode),
+		errors.Is(err, repositories.ErrDuplicateVariant), errors.Is(err, repositories.ErrProductArchived):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repositories.ErrProductNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// HandleProductByID - GET/PUT/PATCH/DELETE /api/produk/{id} dan GET /api/produk/barcode/{code}.
+// Lookup barcode ditangani di sini karena pola "GET /api/produk/barcode/{code}" bentrok
+// dengan "GET /api/produk/{id}/stok-history" di ServeMux.
 func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
+		if code, ok := strings.CutPrefix(r.URL.Path, "/api/produk/barcode/"); ok {
+			h.GetByBarcode(w, r, code)
+			return
+		}
 		h.GetByID(w, r)
 	case http.MethodPut:
 		h.Update(w, r)
+	case http.MethodPatch:
+		h.Patch(w, r)
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
@@ -95,14 +231,16 @@ func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Reques
 
 // GetByID godoc
 // @Summary Get product by ID
-// @Description Mengambil produk berdasarkan ID
+// @Description Mengambil produk berdasarkan ID; produk induk disertai daftar variannya
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 200 {object} models.Product
+// @Header 200 {string} ETag "Versi produk, kirim kembali di If-Match saat PUT/PATCH"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/produk/{id} [get]
 func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
@@ -118,21 +256,53 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, product.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// GetByBarcode godoc
+// @Summary Get product by barcode
+// @Description Mengambil produk berdasarkan barcode hasil scan (EAN-8, EAN-13 atau UPC-A)
+// @Tags Products
+// @Produce json
+// @Param code path string true "Barcode"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/barcode/{code} [get]
+func (h *ProductHandler) GetByBarcode(w http.ResponseWriter, r *http.Request, code string) {
+	product, err := h.service.GetByBarcode(code)
+	if errors.Is(err, repositories.ErrProductNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
 
 // Update godoc
 // @Summary Update product
-// @Description Mengedit produk berdasarkan ID
+// @Description Mengedit produk berdasarkan ID (semua field diganti kecuali stock). Field stock diabaikan supaya penjualan / penerimaan sejak produk dibaca tidak tertimpa; ubah stok lewat PATCH stock atau penyesuaian stok. Kirim version (atau header If-Match) untuk menolak edit jika produk sudah diubah orang lain.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
+// @Param If-Match header string false "ETag / version produk yang diedit"
 // @Param product body models.Product true "Product data"
 // @Success 200 {object} models.Product
+// @Header 200 {string} ETag "Versi produk yang baru"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/produk/{id} [put]
 func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
@@ -148,28 +318,92 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if version > 0 {
+		product.Version = version
+	}
 
 	product.ID = id
-	err = h.service.Update(&product)
+	err = h.service.Update(&product, CurrentUser(r).UserID)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+
+	setETag(w, product.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// Patch godoc
+// @Summary Partially update product
+// @Description Mengubah sebagian field produk; field yang tidak dikirim tidak diubah. tax_rate null = kembali ikut tarif kategori. Stok hanya disesuaikan (mutasi adjustment) jika stock dikirim. Kirim version (atau header If-Match) untuk menolak edit jika produk sudah diubah orang lain.
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param If-Match header string false "ETag / version produk yang diedit"
+// @Param product body models.ProductPatch true "Fields to update"
+// @Success 200 {object} models.Product
+// @Header 200 {string} ETag "Versi produk yang baru"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/{id} [patch]
+func (h *ProductHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var patch models.ProductPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	version, err := ifMatchVersion(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if version > 0 {
+		patch.Version = version
+	}
+
+	product, err := h.service.Patch(id, patch, CurrentUser(r).UserID)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
 
+	setETag(w, product.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
 
 // Delete godoc
-// @Summary Delete product
-// @Description Menghapus produk berdasarkan ID
+// @Summary Archive or delete product
+// @Description Mengarsipkan produk (default): produk disembunyikan dari daftar, pencarian, dan checkout tetapi tetap ada di riwayat transaksi dan laporan; varian ikut diarsipkan. Dengan permanent=true produk dihapus permanen, hanya jika belum pernah dipakai (transaksi, pembelian, opname, promo) dan tidak punya varian.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
+// @Param permanent query bool false "Hard delete (only for unused products)"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/produk/{id} [delete]
 func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
@@ -179,14 +413,182 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Delete(id)
+	permanent := false
+	if value := r.URL.Query().Get("permanent"); value != "" {
+		permanent, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid permanent", http.StatusBadRequest)
+			return
+		}
+	}
+
+	message := "Product archived successfully"
+	if permanent {
+		message = "Product deleted successfully"
+		err = h.service.Delete(id)
+	} else {
+		err = h.service.Archive(id)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProductStateError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Product deleted successfully",
+		"message": message,
 	})
-}
\ No newline at end of file
+}
+
+// Restore godoc
+// @Summary Restore archived product
+// @Description Memulihkan produk yang diarsipkan beserta varian yang ikut diarsipkan bersamanya. Varian hanya bisa dipulihkan jika produk induknya aktif.
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/{id}/pulihkan [post]
+func (h *ProductHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Restore(id); err != nil {
+		writeProductStateError(w, err)
+		return
+	}
+	product, err := h.service.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setETag(w, product.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// writeProductStateError - arsip / pulihkan / hapus: produk tidak ada 404, status tidak sesuai
+// atau masih dipakai 409, selain itu 400
+func writeProductStateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrProductNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrProductHasVariants), errors.Is(err, repositories.ErrProductInUse),
+		errors.Is(err, repositories.ErrProductArchived), errors.Is(err, repositories.ErrProductNotArchived):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// GetStockHistory godoc
+// @Summary Get stock history
+// @Description Mengambil riwayat mutasi stok produk (penjualan, refund, pembelian, adjustment, opname, transfer) dari ledger stok
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param type query string false "Filter jenis mutasi"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} models.StockMovementListResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/{id}/stok-history [get]
+func (h *ProductHandler) GetStockHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.StockMovementFilter{
+		ProductID: id,
+		Type:      q.Get("type"),
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	}
+
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	intParams := map[string]*int{
+		"limit":  &filter.Limit,
+		"offset": &filter.Offset,
+	}
+	for name, dest := range intParams {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dest = n
+	}
+
+	result, err := h.service.GetStockHistory(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// RecordStockMovement godoc
+// @Summary Record stock movement
+// @Description Mencatat mutasi stok manual (adjustment atau transfer) dengan quantity bertanda dan alasan
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body models.StockMovementRequest true "Stock movement"
+// @Success 201 {object} models.StockMovement
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/{id}/stok [post]
+func (h *ProductHandler) RecordStockMovement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.StockMovementRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	movement, err := h.service.RecordStockMovement(id, req, CurrentUser(r).UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
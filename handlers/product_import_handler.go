@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxImportFileSize = 10 << 20
+
+// Import godoc
+// @Summary Import products from CSV / XLSX
+// @Description Import produk massal dari CSV atau XLSX (sheet pertama). Kolom dibaca dari header: id, name, sku, price, cost_price, stock, min_stock, category (nama) atau category_id; kolom lain diabaikan. Baris dengan id (seperti hasil export) atau, jika id kosong, SKU yang sudah ada mengubah produk tersebut (sel kosong = nilai lama dipertahankan); id yang tidak ada ditolak; selain itu dibuat produk baru. Kolom stock hanya mengisi stok awal produk baru; stok produk lama ditimpa hanya jika update_stock aktif (mis. hasil hitung fisik), supaya file export lama tidak membatalkan penjualan sejak export. Jika ada baris yang error tidak ada yang disimpan (422) dan laporan per baris dikembalikan; dry_run hanya memvalidasi.
+// @Tags Products
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV / XLSX file (max 10 MB)"
+// @Param format formData string false "csv or xlsx (default from file extension)"
+// @Param dry_run formData bool false "Validate only, do not save"
+// @Param create_categories formData bool false "Create categories that do not exist yet"
+// @Param update_stock formData bool false "Overwrite stock of existing products with the stock column"
+// @Success 200 {object} models.ProductImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} models.ProductImportResult
+// @Security BearerAuth
+// @Router /api/produk/import [post]
+func (h *ProductHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		http.Error(w, "Invalid multipart form or file larger than 10 MB", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File wajib diupload di field file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	format, err := services.ProductFileFormat(r.FormValue("format"), header.Filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var opts models.ProductImportOptions
+	boolParams := map[string]*bool{
+		"dry_run":           &opts.DryRun,
+		"create_categories": &opts.CreateCategories,
+		"update_stock":      &opts.UpdateStock,
+	}
+	for name, dest := range boolParams {
+		value := r.FormValue(name)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dest = b
+	}
+
+	result, err := h.service.Import(file, format, opts, CurrentUser(r).UserID)
+	if err != nil {
+		writeProductWriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Errors) > 0 && !opts.DryRun {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// Export godoc
+// @Summary Export products to CSV / XLSX
+// @Description Unduh seluruh katalog produk dengan kolom yang sama seperti import (id, sku, name, category_id, category, price, cost_price, stock, min_stock)
+// @Tags Products
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv or xlsx (default csv)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/produk/export [get]
+func (h *ProductHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ProductFileCSV
+	}
+	format, err := services.ProductFileFormat(format, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == models.ProductFileXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="produk-`+time.Now().Format("20060102")+"."+format+`"`)
+
+	if err := h.service.Export(w, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+)
+
+type ReceiptHandler struct {
+	service *services.ReceiptService
+}
+
+func NewReceiptHandler(service *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{service: service}
+}
+
+// GetReceipt godoc
+// @Summary Get transaction receipt
+// @Description Render struk transaksi: text (printer thermal 32/48 kolom), html (browser), atau escpos (raw bytes printer)
+// @Tags Transactions
+// @Produce plain
+// @Produce html
+// @Produce octet-stream
+// @Param id path int true "Transaction ID"
+// @Param format query string false "text, html, atau escpos (default text)"
+// @Param width query int false "Lebar kolom untuk text/escpos: 32 atau 48 (default 32)"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/transaksi/{id}/struk [get]
+func (h *ReceiptHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	width := 0
+	if value := r.URL.Query().Get("width"); value != "" {
+		width, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid width", http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, contentType, err := h.service.Render(id, r.URL.Query().Get("format"), width)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTransactionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
@@ -17,11 +17,12 @@ func NewReportHandler(service *services.ReportService) *ReportHandler {
 
 // HandleDailyReport godoc
 // @Summary Get daily sales report
-// @Description Mengambil laporan penjualan hari ini
+// @Description Mengambil laporan penjualan hari ini, termasuk HPP, laba kotor dan margin per produk dan kategori
 // @Tags Reports
 // @Produce json
 // @Success 200 {object} models.DailySalesReport
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/report/hari-ini [get]
 func (h *ReportHandler) HandleDailyReport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -41,7 +42,7 @@ func (h *ReportHandler) HandleDailyReport(w http.ResponseWriter, r *http.Request
 
 // HandleReport godoc
 // @Summary Get sales report by date range
-// @Description Mengambil laporan penjualan berdasarkan rentang tanggal
+// @Description Mengambil laporan penjualan berdasarkan rentang tanggal, termasuk HPP, laba kotor dan margin per produk dan kategori
 // @Tags Reports
 // @Produce json
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
@@ -49,6 +50,7 @@ func (h *ReportHandler) HandleDailyReport(w http.ResponseWriter, r *http.Request
 // @Success 200 {object} models.DailySalesReport
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/report [get]
 func (h *ReportHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -79,3 +81,75 @@ func (h *ReportHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(report)
 }
+
+// HandleTaxReport godoc
+// @Summary Get tax summary report
+// @Description Mengambil rekap pajak (PPN) dan service charge per tarif dan per hari berdasarkan rentang tanggal
+// @Tags Reports
+// @Produce json
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.TaxReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/report/pajak [get]
+func (h *ReportHandler) HandleTaxReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		http.Error(w, "start_date and end_date are required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetTaxReport(startDate, endDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleCashierReport godoc
+// @Summary Get sales report per cashier
+// @Description Mengambil rekap penjualan, refund, dan uang tunai per kasir berdasarkan rentang tanggal untuk rekonsiliasi laci
+// @Tags Reports
+// @Produce json
+// @Param start_date query string true "Start date (YYYY-MM-DD)"
+// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.CashierSalesReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/report/kasir [get]
+func (h *ReportHandler) HandleCashierReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	if startDate == "" || endDate == "" {
+		http.Error(w, "start_date and end_date are required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetSalesByCashier(startDate, endDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ShiftHandler struct {
+	service *services.ShiftService
+}
+
+func NewShiftHandler(service *services.ShiftService) *ShiftHandler {
+	return &ShiftHandler{service: service}
+}
+
+// writeShiftError - shift tidak ada 404, shift ganda 409, selain itu 400
+func writeShiftError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrShiftNotFound), errors.Is(err, repositories.ErrNoOpenShift):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrShiftAlreadyOpened):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// Open godoc
+// @Summary Open shift
+// @Description Membuka shift kasir dengan modal awal (opening float). Checkout dan refund selama shift terbuka tercatat ke shift ini.
+// @Tags Shifts
+// @Accept json
+// @Produce json
+// @Param X-Terminal-ID header string false "ID terminal/perangkat kasir"
+// @Param request body models.OpenShiftRequest true "Opening float"
+// @Success 201 {object} models.Shift
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift/buka [post]
+func (h *ShiftHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var req models.OpenShiftRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shift, err := h.service.Open(CurrentActor(r), req)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shift)
+}
+
+// Current godoc
+// @Summary Get current shift
+// @Description Mengambil shift user yang sedang terbuka beserta rekap penjualan dan kas diharapkan sejauh ini
+// @Tags Shifts
+// @Produce json
+// @Success 200 {object} models.ShiftReport
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift/aktif [get]
+func (h *ShiftHandler) Current(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Current(CurrentUser(r).UserID)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// AddCashMovement godoc
+// @Summary Pay-in / payout
+// @Description Mencatat uang masuk (pay_in) atau keluar (payout) laci di luar penjualan pada shift yang sedang terbuka
+// @Tags Shifts
+// @Accept json
+// @Produce json
+// @Param request body models.CashMovementRequest true "Type, amount, reason"
+// @Success 201 {object} models.CashMovement
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift/kas [post]
+func (h *ShiftHandler) AddCashMovement(w http.ResponseWriter, r *http.Request) {
+	var req models.CashMovementRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	movement, err := h.service.AddCashMovement(CurrentUser(r).UserID, req)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// Close godoc
+// @Summary Close shift
+// @Description Menutup shift dengan uang tunai yang dihitung; server menghitung kas diharapkan dan selisih (over/short), lalu mengembalikan Z-report
+// @Tags Shifts
+// @Accept json
+// @Produce json
+// @Param request body models.CloseShiftRequest true "Counted cash"
+// @Success 200 {object} models.ShiftReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift/tutup [post]
+func (h *ShiftHandler) Close(w http.ResponseWriter, r *http.Request) {
+	var req models.CloseShiftRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.Close(CurrentUser(r).UserID, req)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetAll godoc
+// @Summary Get shifts
+// @Description Mengambil daftar shift, bisa filter by user, status, dan tanggal buka
+// @Tags Shifts
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param status query string false "open atau closed"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.Shift
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift [get]
+func (h *ShiftHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.ShiftFilter{
+		Status:    q.Get("status"),
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	}
+
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := q.Get("user_id"); value != "" {
+		userID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = userID
+	}
+
+	shifts, err := h.service.GetAll(filter)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shifts)
+}
+
+// GetReport godoc
+// @Summary Get Z-report
+// @Description Mengambil Z-report (laporan akhir shift) berdasarkan ID shift
+// @Tags Shifts
+// @Produce json
+// @Param id path int true "Shift ID"
+// @Success 200 {object} models.ShiftReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/shift/{id}/z-report [get]
+func (h *ShiftHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shift ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetReport(id)
+	if err != nil {
+		writeShiftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"kasir-api/models"
+	"kasir-api/repositories"
 	"kasir-api/services"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type TransactionHandler struct {
@@ -27,14 +32,20 @@ func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Reque
 
 // Checkout godoc
 // @Summary Checkout transaction
-// @Description Membuat transaksi baru dengan daftar produk dan quantity
+// @Description Membuat transaksi baru dengan daftar produk (by product_id atau barcode hasil scan), quantity, dan pembayaran (tunai, QRIS, debit, kredit, e-wallet)
 // @Tags Transactions
 // @Accept json
 // @Produce json
-// @Param request body models.CheckoutRequest true "Checkout items"
+// @Param Idempotency-Key header string false "Key unik per checkout per kasir; retry oleh kasir yang sama dengan key sama mengembalikan transaksi asli"
+// @Param X-Terminal-ID header string false "ID terminal/perangkat kasir, dicatat pada transaksi"
+// @Param X-Supervisor-Token header string false "Override supervisor untuk unit_price manual (dari POST /api/auth/override)"
+// @Param request body models.CheckoutRequest true "Checkout items and payments"
 // @Success 200 {object} models.Transaction
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
 // @Router /api/checkout [post]
 func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	var req models.CheckoutRequest
@@ -49,7 +60,149 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	transaction, err := h.service.Checkout(req.Items)
+	for _, item := range req.Items {
+		if item.UnitPrice != nil && !Allowed(r, models.PermPriceOverride) {
+			forbidden(w, models.PermPriceOverride)
+			return
+		}
+	}
+
+	transaction, replayed, err := h.service.Checkout(req, r.Header.Get("Idempotency-Key"), CurrentActor(r))
+	if errors.Is(err, services.ErrIdempotencyConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repositories.ErrOverrideRejected) {
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleTransactions - GET /api/transaksi
+func (h *TransactionHandler) HandleTransactions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetAll godoc
+// @Summary Get transaction history
+// @Description Mengambil riwayat transaksi beserta detailnya, bisa filter by tanggal, nominal, produk, dan kasir
+// @Tags Transactions
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param min_amount query int false "Minimum total amount"
+// @Param max_amount query int false "Maximum total amount"
+// @Param product_id query int false "Only transactions containing this product"
+// @Param cashier_id query int false "Only transactions rung up by this cashier (user ID)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} models.TransactionListResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/transaksi [get]
+func (h *TransactionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.TransactionFilter{
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	}
+
+	for _, date := range []string{filter.StartDate, filter.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	intParams := map[string]*int{
+		"min_amount": &filter.MinAmount,
+		"max_amount": &filter.MaxAmount,
+		"product_id": &filter.ProductID,
+		"cashier_id": &filter.CashierID,
+		"limit":      &filter.Limit,
+		"offset":     &filter.Offset,
+	}
+	for name, dest := range intParams {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dest = n
+	}
+
+	result, err := h.service.GetAll(filter)
+	if errors.Is(err, services.ErrInvalidTransactionFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleTransactionByID - GET /api/transaksi/{id}
+func (h *TransactionHandler) HandleTransactionByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetByID godoc
+// @Summary Get transaction by ID
+// @Description Mengambil transaksi beserta detail item berdasarkan ID
+// @Tags Transactions
+// @Produce json
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/transaksi/{id} [get]
+func (h *TransactionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/transaksi/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.service.GetByID(id)
+	if errors.Is(err, repositories.ErrTransactionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -58,3 +211,100 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(transaction)
 }
+
+// Void godoc
+// @Summary Void transaction
+// @Description Membatalkan seluruh transaksi dan mengembalikan stok semua item. Refund tunai dicatat di shift terbuka milik user, atau shift transaksi asal jika masih terbuka; jika keduanya tidak ada ditolak (400).
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param X-Supervisor-Token header string false "Override supervisor sekali pakai untuk transaksi ini jika kasir tidak punya izin"
+// @Param id path int true "Transaction ID"
+// @Param request body models.VoidRequest true "Void reason"
+// @Success 201 {object} models.Refund
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/transaksi/{id}/void [post]
+func (h *TransactionHandler) Void(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.VoidRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.service.Void(id, req.Reason, CurrentActor(r))
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+// writeRefundError - transaksi tidak ada 404, override supervisor ditolak 403, permintaan void/refund
+// tidak valid 400, selain itu 500
+func writeRefundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrOverrideRejected):
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+	case errors.Is(err, repositories.ErrInvalidRefund):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Refund godoc
+// @Summary Refund transaction items
+// @Description Mengembalikan sebagian item transaksi (per baris detail) dan mengembalikan stoknya. Nominal per baris termasuk pajak eksklusif dan porsi service charge-nya. Refund tunai dicatat di shift terbuka milik user, atau shift transaksi asal jika masih terbuka; jika keduanya tidak ada ditolak (400).
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param X-Supervisor-Token header string false "Override supervisor sekali pakai untuk transaksi ini jika kasir tidak punya izin"
+// @Param id path int true "Transaction ID"
+// @Param request body models.RefundRequest true "Refund reason and items"
+// @Success 201 {object} models.Refund
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/transaksi/{id}/refund [post]
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.RefundRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.service.Refund(id, req, CurrentActor(r))
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
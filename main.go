@@ -4,6 +4,10 @@
 // @host localhost:3000
 // @BasePath /
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Format: "Bearer {access_token}"
 package main
 
 import (
@@ -12,10 +16,12 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"kasir-api/database"
 	"kasir-api/docs"
 	"kasir-api/handlers"
+	"kasir-api/models"
 	"kasir-api/repositories"
 	"kasir-api/services"
 
@@ -24,17 +30,34 @@ import (
 )
 
 type Config struct {
-	Port        string `mapstructure:"PORT"`
-	DBConn      string `mapstructure:"DB_CONN"`
-	SwaggerHost string `mapstructure:"SWAGGER_HOST"`
+	Port                  string  `mapstructure:"PORT"`
+	DBConn                string  `mapstructure:"DB_CONN"`
+	SwaggerHost           string  `mapstructure:"SWAGGER_HOST"`
+	IdempotencyTTLHours   int     `mapstructure:"IDEMPOTENCY_TTL_HOURS"`
+	TaxRate               float64 `mapstructure:"TAX_RATE"`
+	TaxInclusive          bool    `mapstructure:"TAX_INCLUSIVE"`
+	ServiceChargeRate     float64 `mapstructure:"SERVICE_CHARGE_RATE"`
+	StoreName             string  `mapstructure:"STORE_NAME"`
+	StoreAddress          string  `mapstructure:"STORE_ADDRESS"`
+	StorePhone            string  `mapstructure:"STORE_PHONE"`
+	ReceiptFooter         string  `mapstructure:"RECEIPT_FOOTER"`
+	JWTSecret             string  `mapstructure:"JWT_SECRET"`
+	AccessTokenTTLMinutes int     `mapstructure:"ACCESS_TOKEN_TTL_MINUTES"`
+	RefreshTokenTTLHours  int     `mapstructure:"REFRESH_TOKEN_TTL_HOURS"`
+	AdminUsername         string  `mapstructure:"ADMIN_USERNAME"`
+	AdminPassword         string  `mapstructure:"ADMIN_PASSWORD"`
+	WebhookURL            string  `mapstructure:"NOTIFICATION_WEBHOOK_URL"`
+	WebhookSecret         string  `mapstructure:"NOTIFICATION_WEBHOOK_SECRET"`
+	WebhookIntervalSecs   int     `mapstructure:"NOTIFICATION_WEBHOOK_INTERVAL_SECONDS"`
 }
 
 // CORS middleware
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, X-Supervisor-Token, X-Terminal-ID, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, ETag")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -60,9 +83,33 @@ func main() {
 	}
 
 	config := Config{
-		Port:        viper.GetString("PORT"),
-		DBConn:      viper.GetString("DB_CONN"),
-		SwaggerHost: viper.GetString("SWAGGER_HOST"),
+		Port:                  viper.GetString("PORT"),
+		DBConn:                viper.GetString("DB_CONN"),
+		SwaggerHost:           viper.GetString("SWAGGER_HOST"),
+		IdempotencyTTLHours:   viper.GetInt("IDEMPOTENCY_TTL_HOURS"),
+		TaxRate:               viper.GetFloat64("TAX_RATE"),
+		TaxInclusive:          viper.GetBool("TAX_INCLUSIVE"),
+		ServiceChargeRate:     viper.GetFloat64("SERVICE_CHARGE_RATE"),
+		StoreName:             viper.GetString("STORE_NAME"),
+		StoreAddress:          viper.GetString("STORE_ADDRESS"),
+		StorePhone:            viper.GetString("STORE_PHONE"),
+		ReceiptFooter:         viper.GetString("RECEIPT_FOOTER"),
+		JWTSecret:             viper.GetString("JWT_SECRET"),
+		AccessTokenTTLMinutes: viper.GetInt("ACCESS_TOKEN_TTL_MINUTES"),
+		RefreshTokenTTLHours:  viper.GetInt("REFRESH_TOKEN_TTL_HOURS"),
+		AdminUsername:         viper.GetString("ADMIN_USERNAME"),
+		AdminPassword:         viper.GetString("ADMIN_PASSWORD"),
+		WebhookURL:            viper.GetString("NOTIFICATION_WEBHOOK_URL"),
+		WebhookSecret:         viper.GetString("NOTIFICATION_WEBHOOK_SECRET"),
+		WebhookIntervalSecs:   viper.GetInt("NOTIFICATION_WEBHOOK_INTERVAL_SECONDS"),
+	}
+
+	if config.WebhookIntervalSecs <= 0 {
+		config.WebhookIntervalSecs = 30
+	}
+
+	if config.JWTSecret == "" {
+		log.Fatal("JWT_SECRET wajib diisi")
 	}
 
 	// Override Swagger host/scheme for production
@@ -78,6 +125,18 @@ func main() {
 	}
 	defer db.Close()
 
+	// Dependency Injection - Auth
+	userRepo := repositories.NewUserRepository(db)
+	authService := services.NewAuthService(userRepo, config.JWTSecret,
+		time.Duration(config.AccessTokenTTLMinutes)*time.Minute,
+		time.Duration(config.RefreshTokenTTLHours)*time.Hour,
+	)
+	if err := authService.EnsureInitialUser(config.AdminUsername, config.AdminPassword); err != nil {
+		log.Fatal("Failed to create initial user:", err)
+	}
+	authHandler := handlers.NewAuthHandler(authService)
+	userHandler := handlers.NewUserHandler(authService)
+
 	// Dependency Injection - Category (create first, needed by Product)
 	categoryRepo := repositories.NewCategoryRepository(db)
 	categoryService := services.NewCategoryService(categoryRepo)
@@ -85,19 +144,65 @@ func main() {
 
 	// Dependency Injection - Product
 	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo, categoryRepo)
+	stockRepo := repositories.NewStockRepository(db)
+	productService := services.NewProductService(productRepo, categoryRepo, stockRepo)
 	productHandler := handlers.NewProductHandler(productService)
 
+	// Dependency Injection - Notification (stok menipis dari mutasi stok, opsional dikirim ke webhook)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	notificationService := services.NewNotificationService(notificationRepo, config.WebhookURL, config.WebhookSecret)
+	notificationService.StartWebhookDispatcher(time.Duration(config.WebhookIntervalSecs) * time.Second)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+
+	// Dependency Injection - Supplier & Purchase Order
+	supplierRepo := repositories.NewSupplierRepository(db)
+	supplierService := services.NewSupplierService(supplierRepo)
+	supplierHandler := handlers.NewSupplierHandler(supplierService)
+	purchaseOrderRepo := repositories.NewPurchaseOrderRepository(db)
+	purchaseOrderService := services.NewPurchaseOrderService(purchaseOrderRepo, supplierRepo)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+
+	// Dependency Injection - Stock Opname
+	opnameRepo := repositories.NewOpnameRepository(db)
+	opnameService := services.NewOpnameService(opnameRepo)
+	opnameHandler := handlers.NewOpnameHandler(opnameService)
+
+	// Dependency Injection - Promotion
+	promotionRepo := repositories.NewPromotionRepository(db)
+	promotionService := services.NewPromotionService(promotionRepo, productRepo, categoryRepo)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+
 	// Dependency Injection - Transaction
 	transactionRepo := repositories.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo)
+	transactionService := services.NewTransactionService(transactionRepo, promotionRepo, productRepo,
+		time.Duration(config.IdempotencyTTLHours)*time.Hour,
+		models.TaxSettings{
+			Rate:              config.TaxRate,
+			Inclusive:         config.TaxInclusive,
+			ServiceChargeRate: config.ServiceChargeRate,
+		},
+	)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 
+	// Dependency Injection - Receipt
+	receiptService := services.NewReceiptService(transactionRepo, models.StoreInfo{
+		Name:    config.StoreName,
+		Address: config.StoreAddress,
+		Phone:   config.StorePhone,
+		Footer:  config.ReceiptFooter,
+	})
+	receiptHandler := handlers.NewReceiptHandler(receiptService)
+
 	// Dependency Injection - Report
 	reportRepo := repositories.NewReportRepository(db)
 	reportService := services.NewReportService(reportRepo)
 	reportHandler := handlers.NewReportHandler(reportService)
 
+	// Dependency Injection - Shift
+	shiftRepo := repositories.NewShiftRepository(db)
+	shiftService := services.NewShiftService(shiftRepo, reportRepo)
+	shiftHandler := handlers.NewShiftHandler(shiftService)
+
 	mux := http.NewServeMux()
 
 	// Set DB for health check
@@ -124,23 +229,194 @@ func main() {
 	mux.HandleFunc("GET /health", HealthCheckHandler)
 	mux.HandleFunc("GET /health/db", DBHealthCheckHandler)
 
+	// Auth routes
+	mux.HandleFunc("/api/auth/login", authHandler.HandleLogin)
+	mux.HandleFunc("/api/auth/refresh", authHandler.HandleRefresh)
+	mux.HandleFunc("/api/auth/logout", authHandler.HandleLogout)
+	mux.HandleFunc("/api/auth/me", authHandler.HandleMe)
+	mux.HandleFunc("/api/auth/override", authHandler.HandleOverride)
+
+	// User management routes
+	mux.HandleFunc("/api/users", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermUserManage,
+		http.MethodPost: models.PermUserManage,
+	}, userHandler.HandleUsers))
+	mux.HandleFunc("/api/users/", handlers.Authorize(handlers.Permissions{
+		http.MethodPut: models.PermUserManage,
+	}, userHandler.HandleUserByID))
+
 	// Products routes (layered architecture)
-	mux.HandleFunc("/api/produk", productHandler.HandleProducts)
-	mux.HandleFunc("/api/produk/", productHandler.HandleProductByID)
+	mux.HandleFunc("/api/produk", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermProductRead,
+		http.MethodPost: models.PermProductWrite,
+	}, productHandler.HandleProducts))
+	mux.HandleFunc("/api/produk/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:    models.PermProductRead,
+		http.MethodPut:    models.PermProductWrite,
+		http.MethodPatch:  models.PermProductWrite,
+		http.MethodDelete: models.PermProductDelete,
+	}, productHandler.HandleProductByID))
+	mux.HandleFunc("GET /api/produk/stok-menipis", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermProductRead,
+	}, productHandler.GetLowStock))
+	mux.HandleFunc("GET /api/produk/cari", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermProductRead,
+	}, productHandler.Search))
+	mux.HandleFunc("POST /api/produk/import", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermProductWrite,
+	}, productHandler.Import))
+	mux.HandleFunc("GET /api/produk/export", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermProductRead,
+	}, productHandler.Export))
+	mux.HandleFunc("GET /api/produk/{id}/stok-history", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermProductRead,
+	}, productHandler.GetStockHistory))
+	mux.HandleFunc("POST /api/produk/{id}/stok", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermStockAdjust,
+	}, productHandler.RecordStockMovement))
+	mux.HandleFunc("POST /api/produk/{id}/pulihkan", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermProductDelete,
+	}, productHandler.Restore))
 
 	// Categories routes (layered architecture)
-	mux.HandleFunc("/api/kategori", categoryHandler.HandleCategories)
-	mux.HandleFunc("/api/kategori/", categoryHandler.HandleCategoryByID)
+	mux.HandleFunc("/api/kategori", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermCategoryRead,
+		http.MethodPost: models.PermCategoryWrite,
+	}, categoryHandler.HandleCategories))
+	mux.HandleFunc("/api/kategori/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:    models.PermCategoryRead,
+		http.MethodPut:    models.PermCategoryWrite,
+		http.MethodPatch:  models.PermCategoryWrite,
+		http.MethodDelete: models.PermCategoryWrite,
+	}, categoryHandler.HandleCategoryByID))
+	mux.HandleFunc("POST /api/kategori/{id}/pulihkan", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermCategoryWrite,
+	}, categoryHandler.Restore))
+
+	// Supplier & purchase order routes
+	mux.HandleFunc("/api/supplier", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermPurchase,
+		http.MethodPost: models.PermPurchase,
+	}, supplierHandler.HandleSuppliers))
+	mux.HandleFunc("/api/supplier/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:    models.PermPurchase,
+		http.MethodPut:    models.PermPurchase,
+		http.MethodDelete: models.PermPurchase,
+	}, supplierHandler.HandleSupplierByID))
+	mux.HandleFunc("/api/pembelian", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermPurchase,
+		http.MethodPost: models.PermPurchase,
+	}, purchaseOrderHandler.HandlePurchaseOrders))
+	mux.HandleFunc("/api/pembelian/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermPurchase,
+		http.MethodPut: models.PermPurchase,
+	}, purchaseOrderHandler.HandlePurchaseOrderByID))
+	mux.HandleFunc("POST /api/pembelian/{id}/pesan", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermPurchase,
+	}, purchaseOrderHandler.Order))
+	mux.HandleFunc("POST /api/pembelian/{id}/terima", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermPurchase,
+	}, purchaseOrderHandler.Receive))
+	mux.HandleFunc("POST /api/pembelian/{id}/batal", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermPurchase,
+	}, purchaseOrderHandler.Cancel))
+
+	// Notification routes
+	mux.HandleFunc("GET /api/notifikasi", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermNotification,
+	}, notificationHandler.GetAll))
+	mux.HandleFunc("POST /api/notifikasi/{id}/dibaca", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermNotification,
+	}, notificationHandler.MarkRead))
+
+	// Stock opname routes
+	mux.HandleFunc("/api/opname", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermOpnameCount,
+		http.MethodPost: models.PermOpnameManage,
+	}, opnameHandler.HandleSessions))
+	mux.HandleFunc("GET /api/opname/{id}", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermOpnameCount,
+	}, opnameHandler.GetByID))
+	mux.HandleFunc("POST /api/opname/{id}/hitung", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermOpnameCount,
+	}, opnameHandler.SubmitCounts))
+	mux.HandleFunc("GET /api/opname/{id}/selisih", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermOpnameManage,
+	}, opnameHandler.GetVariance))
+	mux.HandleFunc("POST /api/opname/{id}/finalisasi", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermOpnameManage,
+	}, opnameHandler.Finalize))
+	mux.HandleFunc("POST /api/opname/{id}/batal", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermOpnameManage,
+	}, opnameHandler.Cancel))
+
+	// Promotion routes
+	mux.HandleFunc("/api/promo", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:  models.PermPromotionRead,
+		http.MethodPost: models.PermPromotionWrite,
+	}, promotionHandler.HandlePromotions))
+	mux.HandleFunc("/api/promo/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet:    models.PermPromotionRead,
+		http.MethodPut:    models.PermPromotionWrite,
+		http.MethodDelete: models.PermPromotionWrite,
+	}, promotionHandler.HandlePromotionByID))
 
 	// Transaction routes
-	mux.HandleFunc("/api/checkout", transactionHandler.HandleCheckout)
+	mux.HandleFunc("/api/checkout", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermCheckout,
+	}, transactionHandler.HandleCheckout))
+	mux.HandleFunc("/api/transaksi", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermTransactionRead,
+	}, transactionHandler.HandleTransactions))
+	mux.HandleFunc("/api/transaksi/", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermTransactionRead,
+	}, transactionHandler.HandleTransactionByID))
+	mux.HandleFunc("POST /api/transaksi/{id}/void", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermVoid,
+	}, transactionHandler.Void))
+	mux.HandleFunc("POST /api/transaksi/{id}/refund", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermRefund,
+	}, transactionHandler.Refund))
+	mux.HandleFunc("GET /api/transaksi/{id}/struk", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermTransactionRead,
+	}, receiptHandler.GetReceipt))
+
+	// Shift routes
+	mux.HandleFunc("POST /api/shift/buka", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermShift,
+	}, shiftHandler.Open))
+	mux.HandleFunc("GET /api/shift/aktif", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermShift,
+	}, shiftHandler.Current))
+	mux.HandleFunc("POST /api/shift/kas", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermShift,
+	}, shiftHandler.AddCashMovement))
+	mux.HandleFunc("POST /api/shift/tutup", handlers.Authorize(handlers.Permissions{
+		http.MethodPost: models.PermShift,
+	}, shiftHandler.Close))
+	mux.HandleFunc("GET /api/shift", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermShiftRead,
+	}, shiftHandler.GetAll))
+	mux.HandleFunc("GET /api/shift/{id}/z-report", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermShiftRead,
+	}, shiftHandler.GetReport))
 
 	// Report routes
-	mux.HandleFunc("/api/report/hari-ini", reportHandler.HandleDailyReport)
-	mux.HandleFunc("/api/report", reportHandler.HandleReport)
+	mux.HandleFunc("/api/report/hari-ini", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermReportRead,
+	}, reportHandler.HandleDailyReport))
+	mux.HandleFunc("/api/report/pajak", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermReportRead,
+	}, reportHandler.HandleTaxReport))
+	mux.HandleFunc("/api/report/kasir", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermReportRead,
+	}, reportHandler.HandleCashierReport))
+	mux.HandleFunc("/api/report", handlers.Authorize(handlers.Permissions{
+		http.MethodGet: models.PermReportRead,
+	}, reportHandler.HandleReport))
 
-	// Wrap with CORS middleware
-	handler := corsMiddleware(mux)
+	// Wrap with auth middleware (semua /api kecuali login/refresh), lalu CORS
+	handler := corsMiddleware(authHandler.Middleware(mux))
 
 	// Start server
 	addr := "0.0.0.0:" + config.Port
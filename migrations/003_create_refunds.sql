@@ -0,0 +1,26 @@
+-- Status transaksi: completed, partially_refunded, refunded, voided
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed';
+
+-- Tabel untuk menyimpan void / refund transaksi
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('void', 'refund')),
+    reason TEXT NOT NULL,
+    total_amount INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tabel untuk menyimpan item yang dikembalikan per void / refund
+CREATE TABLE IF NOT EXISTS refund_details (
+    id SERIAL PRIMARY KEY,
+    refund_id INT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    transaction_detail_id INT NOT NULL REFERENCES transaction_details(id) ON DELETE CASCADE,
+    product_id INT REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction_id ON refunds(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_created_at ON refunds(created_at);
+CREATE INDEX IF NOT EXISTS idx_refund_details_transaction_detail_id ON refund_details(transaction_detail_id);
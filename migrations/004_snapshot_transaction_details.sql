@@ -0,0 +1,25 @@
+-- Snapshot data produk saat checkout, supaya rename/hapus produk tidak mengubah riwayat
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS product_name VARCHAR(255);
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS unit_price INT;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS category_id INT;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS category_name VARCHAR(255);
+
+-- Backfill data lama dari tabel products/categories saat ini
+UPDATE transaction_details td
+SET product_name = p.name,
+    category_id = p.category_id,
+    category_name = c.name
+FROM products p
+LEFT JOIN categories c ON p.category_id = c.id
+WHERE td.product_id = p.id
+  AND td.product_name IS NULL;
+
+UPDATE transaction_details
+SET unit_price = subtotal / NULLIF(quantity, 0)
+WHERE unit_price IS NULL;
+
+UPDATE transaction_details SET product_name = '' WHERE product_name IS NULL;
+UPDATE transaction_details SET unit_price = 0 WHERE unit_price IS NULL;
+
+ALTER TABLE transaction_details ALTER COLUMN product_name SET NOT NULL;
+ALTER TABLE transaction_details ALTER COLUMN unit_price SET NOT NULL;
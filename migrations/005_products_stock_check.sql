@@ -0,0 +1,10 @@
+-- Pengaman terakhir supaya stok tidak pernah negatif.
+-- NOT VALID: data lama tidak dicek ulang, tapi semua INSERT/UPDATE berikutnya wajib memenuhi.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'products_stock_non_negative'
+    ) THEN
+        ALTER TABLE products ADD CONSTRAINT products_stock_non_negative CHECK (stock >= 0) NOT VALID;
+    END IF;
+END $$;
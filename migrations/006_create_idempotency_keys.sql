@@ -0,0 +1,10 @@
+-- Tabel untuk menyimpan Idempotency-Key checkout beserta response aslinya
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    transaction_id INT REFERENCES transactions(id) ON DELETE CASCADE,
+    response JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
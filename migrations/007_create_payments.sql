@@ -0,0 +1,26 @@
+-- Total uang yang diterima dan kembalian per transaksi
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS paid_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS change_amount INT NOT NULL DEFAULT 0;
+
+-- Tabel untuk menyimpan pembayaran per transaksi (bisa lebih dari satu metode)
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('cash', 'qris', 'debit', 'credit', 'ewallet')),
+    amount INT NOT NULL,
+    tendered INT NOT NULL,
+    change_amount INT NOT NULL DEFAULT 0,
+    reference VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_transaction_id ON payments(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_payments_method ON payments(method);
+
+-- Transaksi lama dianggap dibayar tunai pas
+UPDATE transactions SET paid_amount = total_amount WHERE paid_amount = 0;
+
+INSERT INTO payments (transaction_id, method, amount, tendered, change_amount, created_at)
+SELECT t.id, 'cash', t.total_amount, t.total_amount, 0, t.created_at
+FROM transactions t
+WHERE NOT EXISTS (SELECT 1 FROM payments p WHERE p.transaction_id = t.id);
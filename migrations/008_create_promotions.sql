@@ -0,0 +1,30 @@
+-- Tabel untuk menyimpan promo / diskon
+CREATE TABLE IF NOT EXISTS promotions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed', 'buy_x_get_y', 'bundle', 'category')),
+    value INT NOT NULL DEFAULT 0,
+    product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    buy_qty INT NOT NULL DEFAULT 0,
+    get_qty INT NOT NULL DEFAULT 0,
+    bundle_qty INT NOT NULL DEFAULT 0,
+    bundle_price INT NOT NULL DEFAULT 0,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_period ON promotions(start_at, end_at) WHERE is_active;
+
+-- Subtotal (sebelum diskon) dan diskon per transaksi; total_amount adalah nilai setelah diskon
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS subtotal_amount INT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS discount_amount INT NOT NULL DEFAULT 0;
+UPDATE transactions SET subtotal_amount = total_amount WHERE subtotal_amount IS NULL;
+ALTER TABLE transactions ALTER COLUMN subtotal_amount SET NOT NULL;
+
+-- Diskon per baris; subtotal adalah nilai setelah diskon
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS discount_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS promotion_id INT REFERENCES promotions(id) ON DELETE SET NULL;
@@ -0,0 +1,12 @@
+-- Tarif pajak (persen) per produk / kategori; NULL = ikut tarif default dari konfigurasi
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5,2);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5,2);
+
+-- Rincian pajak dan service charge per transaksi; total_amount adalah grand total
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tax_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS service_charge_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Tarif dan nominal pajak per baris
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5,2) NOT NULL DEFAULT 0;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS tax_amount INT NOT NULL DEFAULT 0;
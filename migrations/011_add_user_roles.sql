@@ -0,0 +1,14 @@
+-- Role user: cashier, supervisor, owner
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'cashier';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_check') THEN
+        ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('cashier', 'supervisor', 'owner'));
+    END IF;
+END $$;
+
+-- User pertama (dibuat dari ADMIN_USERNAME) menjadi owner supaya bisa mengelola user lain
+UPDATE users SET role = 'owner'
+WHERE id = (SELECT MIN(id) FROM users)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'owner');
@@ -0,0 +1,12 @@
+-- Kasir, terminal, dan supervisor yang menyetujui override pada setiap transaksi
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS cashier_id INT REFERENCES users(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS terminal_id VARCHAR(100);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS approved_by INT REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_cashier_created ON transactions(cashier_id, created_at);
+
+-- User yang memproses void/refund (uang keluar dari laci user tersebut)
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users(id);
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS approved_by INT REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_user_created ON refunds(user_id, created_at);
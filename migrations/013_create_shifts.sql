@@ -0,0 +1,37 @@
+-- Shift kasir: dibuka dengan modal awal (opening float), ditutup dengan uang yang dihitung
+CREATE TABLE IF NOT EXISTS shifts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    terminal_id VARCHAR(100),
+    opening_float INT NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    opened_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP,
+    counted_cash INT,
+    expected_cash INT,
+    over_short INT,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+-- Satu user hanya boleh punya satu shift yang terbuka
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shifts_user_open ON shifts(user_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_shifts_opened_at ON shifts(opened_at);
+
+-- Uang masuk/keluar laci di luar penjualan (pay-in, payout)
+CREATE TABLE IF NOT EXISTS cash_movements (
+    id SERIAL PRIMARY KEY,
+    shift_id INT NOT NULL REFERENCES shifts(id),
+    type VARCHAR(20) NOT NULL,
+    amount INT NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL DEFAULT '',
+    user_id INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_movements_shift_id ON cash_movements(shift_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS shift_id INT REFERENCES shifts(id);
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS shift_id INT REFERENCES shifts(id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_shift_id ON transactions(shift_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_shift_id ON refunds(shift_id);
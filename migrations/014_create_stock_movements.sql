@@ -0,0 +1,36 @@
+-- Ledger stok append-only. products.stock tetap disimpan sebagai saldo berjalan dan selalu
+-- diubah bersamaan dengan baris ledger di DB transaction yang sama.
+-- product_id sengaja tanpa foreign key supaya riwayat tetap ada walau produk dihapus.
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    quantity INT NOT NULL,
+    stock_after INT NOT NULL,
+    reference_type VARCHAR(30),
+    reference_id INT,
+    note TEXT NOT NULL DEFAULT '',
+    user_id INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_created ON stock_movements(product_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_reference ON stock_movements(reference_type, reference_id);
+
+-- Tolak UPDATE/DELETE supaya ledger tidak bisa diubah
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements bersifat append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_stock_movements_append_only ON stock_movements;
+CREATE TRIGGER trg_stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- Saldo awal produk yang sudah ada, supaya jumlah ledger sama dengan products.stock
+INSERT INTO stock_movements (product_id, type, quantity, stock_after, note)
+SELECT p.id, 'initial', p.stock, p.stock, 'Saldo awal saat ledger stok diaktifkan'
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM stock_movements sm WHERE sm.product_id = p.id);
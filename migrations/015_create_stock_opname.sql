@@ -0,0 +1,37 @@
+-- Sesi stock opname (hitung fisik stok)
+CREATE TABLE IF NOT EXISTS opname_sessions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finalized_by INT REFERENCES users(id),
+    finalized_at TIMESTAMP,
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+-- Hasil hitung per produk per perangkat. Perangkat yang mengirim ulang menimpa hitungannya sendiri,
+-- hitungan dari perangkat berbeda dijumlahkan (mis. rak toko + gudang).
+CREATE TABLE IF NOT EXISTS opname_counts (
+    id SERIAL PRIMARY KEY,
+    session_id INT NOT NULL REFERENCES opname_sessions(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    device_id VARCHAR(100) NOT NULL DEFAULT '',
+    counted_qty INT NOT NULL CHECK (counted_qty >= 0),
+    user_id INT REFERENCES users(id),
+    counted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (session_id, product_id, device_id)
+);
+
+-- Selisih yang dibekukan saat sesi difinalisasi
+CREATE TABLE IF NOT EXISTS opname_results (
+    id SERIAL PRIMARY KEY,
+    session_id INT NOT NULL REFERENCES opname_sessions(id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    unit_price INT NOT NULL,
+    system_qty INT NOT NULL,
+    counted_qty INT NOT NULL,
+    UNIQUE (session_id, product_id)
+);
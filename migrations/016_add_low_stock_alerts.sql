@@ -0,0 +1,21 @@
+-- Ambang stok menipis per produk; 0 = tidak dipantau
+ALTER TABLE products ADD COLUMN IF NOT EXISTS min_stock INT NOT NULL DEFAULT 0;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_min_stock_check;
+ALTER TABLE products ADD CONSTRAINT products_min_stock_check CHECK (min_stock >= 0);
+
+-- Notifikasi in-app sekaligus outbox webhook. Baris ditulis di DB transaction yang sama
+-- dengan mutasi stok, lalu dikirim ke webhook oleh dispatcher di background.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    delivery_attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_created ON notifications(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_undelivered ON notifications(id) WHERE delivered_at IS NULL;
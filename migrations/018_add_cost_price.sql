@@ -0,0 +1,7 @@
+-- Harga pokok (HPP) per produk, rata-rata tertimbang dari penerimaan barang
+ALTER TABLE products ADD COLUMN IF NOT EXISTS cost_price INT NOT NULL DEFAULT 0;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_cost_price_check;
+ALTER TABLE products ADD CONSTRAINT products_cost_price_check CHECK (cost_price >= 0);
+
+-- Snapshot HPP per unit saat checkout; transaksi lama bernilai 0 (HPP tidak diketahui)
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS unit_cost INT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- SKU unik per produk (opsional)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products(sku);
+
+-- Satu produk boleh punya beberapa barcode (EAN-8, EAN-13; UPC-A disimpan sebagai EAN-13 dengan awalan 0)
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    code VARCHAR(13) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_product ON product_barcodes(product_id);
@@ -0,0 +1,12 @@
+-- Varian produk (ukuran, warna, rasa) disimpan sebagai baris products dengan parent_id.
+-- Harga, stok, SKU dan barcode per varian; nama dan kategori mengikuti produk induk.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES products(id);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_name VARCHAR(100);
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_variant_check;
+ALTER TABLE products ADD CONSTRAINT products_variant_check CHECK ((parent_id IS NULL) = (variant_name IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_products_parent ON products(parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_parent_variant ON products(parent_id, variant_name) WHERE parent_id IS NOT NULL;
+
+-- Snapshot produk induk saat checkout supaya laporan bisa digabung per produk induk
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS parent_product_id INT;
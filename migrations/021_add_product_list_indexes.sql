@@ -0,0 +1,15 @@
+-- Index untuk sort & filter daftar produk (GET /api/produk). Setiap index diakhiri id
+-- supaya urutan stabil (tie-breaker) dan LIMIT/OFFSET bisa dibaca langsung dari index.
+DROP INDEX IF EXISTS idx_products_name;
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
+CREATE INDEX IF NOT EXISTS idx_products_stock_id ON products(stock, id);
+CREATE INDEX IF NOT EXISTS idx_products_created_id ON products(created_at, id);
+
+-- Filter kategori paling sering dipakai bersama sort default (nama)
+CREATE INDEX IF NOT EXISTS idx_products_category_name ON products(category_id, name, id);
+
+-- Filter stok menipis (juga dipakai GET /api/produk/stok-menipis): partial index hanya
+-- berisi produk yang dipantau dan sedang di bawah ambang
+CREATE INDEX IF NOT EXISTS idx_products_low_stock ON products(id)
+    WHERE min_stock > 0 AND stock <= min_stock;
@@ -0,0 +1,17 @@
+-- Pencarian produk (GET /api/produk/cari): full-text + trigram supaya typo seperti
+-- "indomi" tetap ketemu "Indomie". Butuh PostgreSQL 12+ (generated column).
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Dokumen full-text dari nama + SKU. Konfigurasi 'simple' (tanpa stemming) karena nama
+-- produk kebanyakan merek, bukan kata baku.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || COALESCE(sku, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector);
+
+-- Trigram untuk fuzzy match; juga membuat filter ILIKE '%name%' di GET /api/produk bisa pakai index
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops);
+
+-- Prefix match barcode (kasir mengetik sebagian digit)
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_code_prefix ON product_barcodes(code varchar_pattern_ops);
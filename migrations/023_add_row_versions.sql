@@ -0,0 +1,5 @@
+-- Nomor versi untuk optimistic concurrency (PUT/PATCH dengan field version atau header If-Match).
+-- Naik setiap data produk / kategori diedit; mutasi stok (penjualan, penerimaan, opname) tidak
+-- menaikkan versi supaya edit harga tidak bentrok dengan transaksi di kasir.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
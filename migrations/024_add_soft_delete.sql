@@ -0,0 +1,9 @@
+-- Arsip (soft delete) produk dan kategori. Baris yang diarsipkan disembunyikan dari daftar,
+-- pencarian, export, dan checkout, tetapi tetap ada untuk riwayat transaksi dan laporan.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Hapus kategori tidak lagi mengosongkan kategori produk diam-diam; hapus permanen hanya
+-- boleh untuk kategori yang tidak dipakai produk mana pun (termasuk produk yang diarsipkan)
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_category_id_fkey;
+ALTER TABLE products ADD CONSTRAINT products_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories(id);
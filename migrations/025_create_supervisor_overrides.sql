@@ -0,0 +1,14 @@
+-- Override supervisor sekali pakai. Token hanya membawa jti; kasir yang meminta, izin, dan transaksi
+-- target disimpan di sini. used_at diisi di DB transaction yang sama dengan aksi yang disetujui.
+CREATE TABLE IF NOT EXISTS supervisor_overrides (
+    jti VARCHAR(64) PRIMARY KEY,
+    permission VARCHAR(50) NOT NULL,
+    approver_id INT NOT NULL REFERENCES users(id),
+    requested_by INT NOT NULL REFERENCES users(id),
+    transaction_id INT REFERENCES transactions(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_supervisor_overrides_expires ON supervisor_overrides(expires_at) WHERE used_at IS NULL;
@@ -0,0 +1,5 @@
+-- Stok sistem saat produk dihitung. Selisih opname = counted_qty - system_qty, sehingga penjualan
+-- dan penerimaan yang terjadi antara hitung fisik dan finalisasi tidak ikut dihapus.
+ALTER TABLE opname_counts ADD COLUMN IF NOT EXISTS system_qty INT;
+UPDATE opname_counts c SET system_qty = p.stock FROM products p WHERE p.id = c.product_id AND c.system_qty IS NULL;
+ALTER TABLE opname_counts ALTER COLUMN system_qty SET NOT NULL;
@@ -0,0 +1,5 @@
+-- Idempotency-Key berlaku per kasir: dua terminal yang kebetulan mengirim key yang sama tidak saling
+-- menerima transaksi milik kasir lain. cashier_id 0 untuk checkout tanpa user.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS cashier_id INT NOT NULL DEFAULT 0;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (cashier_id, key);
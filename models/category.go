@@ -1,7 +1,22 @@
 package models
 
+import "time"
+
+// Category - TaxRate dalam persen; null = ikut tarif default.
+// DeletedAt terisi jika kategori diarsipkan; produknya tetap memakai kategori ini.
 type Category struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	TaxRate     *float64   `json:"tax_rate"`
+	Version     int        `json:"version"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CategoryPatch - body PATCH /api/kategori/{id}; field yang tidak dikirim tidak diubah
+type CategoryPatch struct {
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	TaxRate     NullableRate `json:"tax_rate" swaggertype:"number"`
+	Version     int          `json:"version"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const NotificationLowStock = "stock.low"
+
+// Notification - notifikasi in-app; Payload berisi data event sesuai Type (mis. LowStockEvent)
+type Notification struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Message   string          `json:"message"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool
+	Limit      int
+}
+
+// LowStockEvent - stok produk turun sampai atau di bawah MinStock karena mutasi MovementType
+type LowStockEvent struct {
+	ProductID     int    `json:"product_id"`
+	ProductName   string `json:"product_name"`
+	Stock         int    `json:"stock"`
+	MinStock      int    `json:"min_stock"`
+	MovementType  string `json:"movement_type"`
+	ReferenceType string `json:"reference_type,omitempty"`
+	ReferenceID   int    `json:"reference_id,omitempty"`
+}
+
+// WebhookEvent - body yang dikirim ke NOTIFICATION_WEBHOOK_URL. ID sama dengan ID notifikasi
+// dan bisa dipakai penerima untuk dedup karena pengiriman bersifat at-least-once.
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Event     string          `json:"event"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
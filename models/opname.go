@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+const (
+	OpnameStatusOpen      = "open"
+	OpnameStatusFinalized = "finalized"
+	OpnameStatusCancelled = "cancelled"
+
+	StockReferenceOpname = "opname"
+)
+
+// OpnameSession - JumlahProduk adalah jumlah produk yang sudah dihitung di sesi ini
+type OpnameSession struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Note         string     `json:"note"`
+	Status       string     `json:"status"`
+	CreatedBy    int        `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinalizedBy  int        `json:"finalized_by,omitempty"`
+	FinalizedAt  *time.Time `json:"finalized_at,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	JumlahProduk int        `json:"jumlah_produk"`
+}
+
+type CreateOpnameRequest struct {
+	Name string `json:"name"`
+	Note string `json:"note"`
+}
+
+type OpnameCountInput struct {
+	ProductID  int `json:"product_id"`
+	CountedQty int `json:"counted_qty"`
+}
+
+type OpnameCountRequest struct {
+	Items []OpnameCountInput `json:"items"`
+}
+
+type OpnameCount struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"session_id"`
+	ProductID  int       `json:"product_id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	CountedQty int       `json:"counted_qty"`
+	SystemQty  int       `json:"system_qty"`
+	UserID     int       `json:"user_id,omitempty"`
+	CountedAt  time.Time `json:"counted_at"`
+}
+
+type FinalizeOpnameRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OpnameVarianceItem - Selisih = counted - system; NilaiSelisih = Selisih * harga produk
+type OpnameVarianceItem struct {
+	ProductID    int    `json:"product_id"`
+	ProductName  string `json:"product_name"`
+	UnitPrice    int    `json:"unit_price"`
+	SystemQty    int    `json:"system_qty"`
+	CountedQty   int    `json:"counted_qty"`
+	Selisih      int    `json:"selisih"`
+	NilaiSelisih int    `json:"nilai_selisih"`
+}
+
+// OpnameVarianceReport - selisih per produk yang dihitung. system_qty adalah stok sistem saat
+// produk mulai dihitung (bukan stok saat ini), baik untuk sesi open maupun final.
+// NilaiKurang dan NilaiLebih bernilai positif; NilaiBersih = NilaiLebih - NilaiKurang.
+type OpnameVarianceReport struct {
+	Session     *OpnameSession       `json:"session"`
+	Items       []OpnameVarianceItem `json:"items"`
+	NilaiKurang int                  `json:"nilai_kurang"`
+	NilaiLebih  int                  `json:"nilai_lebih"`
+	NilaiBersih int                  `json:"nilai_bersih"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	PaymentMethodCash    = "cash"
+	PaymentMethodQRIS    = "qris"
+	PaymentMethodDebit   = "debit"
+	PaymentMethodCredit  = "credit"
+	PaymentMethodEWallet = "ewallet"
+)
+
+// PaymentMethods - metode pembayaran yang diterima saat checkout
+var PaymentMethods = []string{
+	PaymentMethodCash,
+	PaymentMethodQRIS,
+	PaymentMethodDebit,
+	PaymentMethodCredit,
+	PaymentMethodEWallet,
+}
+
+type Payment struct {
+	ID            int       `json:"id"`
+	TransactionID int       `json:"transaction_id"`
+	Method        string    `json:"method"`
+	Amount        int       `json:"amount"`
+	Tendered      int       `json:"tendered"`
+	ChangeAmount  int       `json:"change_amount"`
+	Reference     string    `json:"reference,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PaymentInput struct {
+	Method    string `json:"method"`
+	Amount    int    `json:"amount"`
+	Reference string `json:"reference,omitempty"`
+}
+
+type PaymentMethodSummary struct {
+	Metode          string `json:"metode"`
+	Total           int    `json:"total"`
+	JumlahTransaksi int    `json:"jumlah_transaksi"`
+}
@@ -1,10 +1,72 @@
 package models
 
+import "time"
+
+// Product - TaxRate dalam persen; null = ikut tarif kategori / default.
+// MinStock adalah ambang stok menipis (reorder point); 0 = tidak dipantau.
+// CostPrice adalah HPP per unit, diperbarui rata-rata tertimbang setiap penerimaan barang.
+// Barcodes berisi EAN-8/EAN-13; UPC-A disimpan sebagai EAN-13 dengan awalan 0.
+// Varian adalah produk dengan ParentID dan VariantName; nama varian = "<nama induk> - <variant_name>"
+// dan kategorinya mengikuti induk. Produk induk yang punya varian tidak bisa dijual langsung.
+// Variants hanya diisi pada detail produk induk.
+// Version naik setiap kali data produk diedit; kirim kembali saat PUT/PATCH untuk mencegah menimpa
+// perubahan orang lain.
+// DeletedAt terisi jika produk diarsipkan: tidak tampil di daftar / pencarian dan tidak bisa dijual.
 type Product struct {
-	ID    int 		`json:"id"`
-	Name  string	`json:"name"`
-	Price int		`json:"price"`
-	Stock int		`json:"stock"`
-	CategoryID int	`json:"category_id"`
-	CategoryName string `json:"category_name,omitempty"`
-}
\ No newline at end of file
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	ParentID     int        `json:"parent_id,omitempty"`
+	VariantName  string     `json:"variant_name,omitempty"`
+	SKU          string     `json:"sku"`
+	Barcodes     []string   `json:"barcodes"`
+	Price        int        `json:"price"`
+	CostPrice    int        `json:"cost_price"`
+	Stock        int        `json:"stock"`
+	MinStock     int        `json:"min_stock"`
+	CategoryID   int        `json:"category_id"`
+	CategoryName string     `json:"category_name,omitempty"`
+	TaxRate      *float64   `json:"tax_rate"`
+	Variants     []Product  `json:"variants,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Version      int        `json:"version"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ProductPatch - body PATCH /api/produk/{id}; field yang tidak dikirim tidak diubah.
+// Stok hanya disesuaikan (mutasi "adjustment") jika stock dikirim.
+type ProductPatch struct {
+	Name        *string      `json:"name"`
+	VariantName *string      `json:"variant_name"`
+	SKU         *string      `json:"sku"`
+	Barcodes    *[]string    `json:"barcodes"`
+	Price       *int         `json:"price"`
+	CostPrice   *int         `json:"cost_price"`
+	Stock       *int         `json:"stock"`
+	MinStock    *int         `json:"min_stock"`
+	CategoryID  *int         `json:"category_id"`
+	TaxRate     NullableRate `json:"tax_rate" swaggertype:"number"`
+	Version     int          `json:"version"`
+}
+
+// ProductFilter - filter, urutan, dan pagination daftar produk.
+// Sort: name, price, stock, created_at; Order: asc, desc. Archived true = hanya produk yang diarsipkan.
+type ProductFilter struct {
+	Name       string
+	CategoryID int
+	MinPrice   int
+	MaxPrice   int
+	InStock    bool
+	LowStock   bool
+	Archived   bool
+	Sort       string
+	Order      string
+	Limit      int
+	Offset     int
+}
+
+type ProductListResponse struct {
+	Data   []Product `json:"data"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
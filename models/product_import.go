@@ -0,0 +1,74 @@
+package models
+
+const (
+	ProductFileCSV  = "csv"
+	ProductFileXLSX = "xlsx"
+
+	ProductImportCreate = "create"
+	ProductImportUpdate = "update"
+)
+
+// ProductFileColumns - urutan kolom file export; import membaca kolom berdasarkan header
+// sehingga urutan bebas dan kolom lain diabaikan. Kolom id dipakai import untuk mencocokkan produk lama.
+var ProductFileColumns = []string{"id", "sku", "name", "category_id", "category", "price", "cost_price", "stock", "min_stock"}
+
+// ProductImportOptions - DryRun hanya memvalidasi tanpa menyimpan; CreateCategories membuat
+// kategori yang belum ada (dicocokkan by nama, tidak case-sensitive). UpdateStock menimpa stok
+// produk lama dengan kolom stock (mis. hasil hitung fisik); tanpa opsi ini kolom stock hanya dipakai
+// sebagai stok awal produk baru, supaya file export lama tidak membatalkan penjualan sejak export.
+type ProductImportOptions struct {
+	DryRun           bool
+	CreateCategories bool
+	UpdateStock      bool
+}
+
+// ProductImportRow - satu baris file yang sudah diparse. Field pointer nil berarti sel kosong:
+// produk baru memakai nilai default, produk lama (cocok by ID, atau by SKU jika ID kosong)
+// mempertahankan nilai lamanya.
+type ProductImportRow struct {
+	Row          int
+	ID           int
+	SKU          string
+	Name         string
+	CategoryID   int
+	CategoryName string
+	Price        *int
+	CostPrice    *int
+	Stock        *int
+	MinStock     *int
+}
+
+// ProductImportItem - hasil validasi satu baris: dibuat baru atau mengubah produk ProductID
+type ProductImportItem struct {
+	Row       int    `json:"row"`
+	Action    string `json:"action"`
+	ProductID int    `json:"product_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Name      string `json:"name"`
+}
+
+type ProductImportError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProductImportResult - laporan import. Jika ada error, tidak ada satu baris pun yang disimpan.
+type ProductImportResult struct {
+	DryRun            bool                 `json:"dry_run"`
+	TotalRows         int                  `json:"total_rows"`
+	Created           int                  `json:"created"`
+	Updated           int                  `json:"updated"`
+	CategoriesCreated []string             `json:"categories_created"`
+	Items             []ProductImportItem  `json:"items"`
+	Errors            []ProductImportError `json:"errors"`
+}
+
+// ProductImportChange - satu baris import yang sudah divalidasi dan siap disimpan. Product.ID 0 berarti
+// produk baru. NewCategory diisi jika kategorinya ikut dibuat oleh import (ID baru ada saat disimpan).
+// SetStock false berarti stok produk lama tidak diubah (kolom stock kosong atau UpdateStock tidak aktif).
+type ProductImportChange struct {
+	Product     *Product
+	NewCategory string
+	SetStock    bool
+}
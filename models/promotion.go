@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+const (
+	PromotionTypePercentage = "percentage"
+	PromotionTypeFixed      = "fixed"
+	PromotionTypeBuyXGetY   = "buy_x_get_y"
+	PromotionTypeBundle     = "bundle"
+	PromotionTypeCategory   = "category"
+)
+
+// PromotionTypes - jenis promo yang didukung
+var PromotionTypes = []string{
+	PromotionTypePercentage,
+	PromotionTypeFixed,
+	PromotionTypeBuyXGetY,
+	PromotionTypeBundle,
+	PromotionTypeCategory,
+}
+
+// Promotion - promo produk/kategori dengan periode berlaku.
+// Value berarti persen untuk percentage/category dan rupiah per unit untuk fixed.
+type Promotion struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Value       int       `json:"value"`
+	ProductID   int       `json:"product_id,omitempty"`
+	CategoryID  int       `json:"category_id,omitempty"`
+	BuyQty      int       `json:"buy_qty,omitempty"`
+	GetQty      int       `json:"get_qty,omitempty"`
+	BundleQty   int       `json:"bundle_qty,omitempty"`
+	BundlePrice int       `json:"bundle_price,omitempty"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	IsActive    bool      `json:"is_active"`
+}
+
+// AppliesTo - promo berlaku untuk produk / kategori ini. parentID adalah produk induk jika produknya
+// varian (0 jika bukan); promo produk pada induk berlaku untuk semua variannya, dihitung per baris varian.
+func (p Promotion) AppliesTo(productID, parentID, categoryID int) bool {
+	if p.Type == PromotionTypeCategory {
+		return categoryID > 0 && p.CategoryID == categoryID
+	}
+	return p.ProductID == productID || (parentID > 0 && p.ProductID == parentID)
+}
+
+// DiscountFor - nominal diskon untuk satu baris belanja, tidak pernah melebihi subtotal baris
+func (p Promotion) DiscountFor(unitPrice, quantity int) int {
+	gross := unitPrice * quantity
+	discount := 0
+
+	switch p.Type {
+	case PromotionTypePercentage, PromotionTypeCategory:
+		discount = gross * p.Value / 100
+	case PromotionTypeFixed:
+		discount = min(p.Value, unitPrice) * quantity
+	case PromotionTypeBuyXGetY:
+		if p.BuyQty > 0 && p.GetQty > 0 {
+			free := quantity / (p.BuyQty + p.GetQty) * p.GetQty
+			discount = free * unitPrice
+		}
+	case PromotionTypeBundle:
+		if p.BundleQty > 0 {
+			bundles := quantity / p.BundleQty
+			discount = bundles * (p.BundleQty*unitPrice - p.BundlePrice)
+		}
+	}
+
+	return max(0, min(discount, gross))
+}
@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+func TestPromotionDiscountFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		promo     Promotion
+		unitPrice int
+		quantity  int
+		want      int
+	}{
+		{name: "persen", promo: Promotion{Type: PromotionTypePercentage, Value: 10}, unitPrice: 15000, quantity: 3, want: 4500},
+		{name: "persen dibulatkan ke bawah", promo: Promotion{Type: PromotionTypePercentage, Value: 15}, unitPrice: 999, quantity: 1, want: 149},
+		{name: "persen di atas 100 dibatasi subtotal", promo: Promotion{Type: PromotionTypePercentage, Value: 150}, unitPrice: 5000, quantity: 2, want: 10000},
+		{name: "kategori", promo: Promotion{Type: PromotionTypeCategory, Value: 20}, unitPrice: 5000, quantity: 2, want: 2000},
+		{name: "potongan per unit", promo: Promotion{Type: PromotionTypeFixed, Value: 1000}, unitPrice: 5000, quantity: 3, want: 3000},
+		{name: "potongan melebihi harga unit", promo: Promotion{Type: PromotionTypeFixed, Value: 8000}, unitPrice: 5000, quantity: 2, want: 10000},
+		{name: "beli 2 gratis 1", promo: Promotion{Type: PromotionTypeBuyXGetY, BuyQty: 2, GetQty: 1}, unitPrice: 4000, quantity: 7, want: 8000},
+		{name: "beli 2 gratis 1 belum cukup", promo: Promotion{Type: PromotionTypeBuyXGetY, BuyQty: 2, GetQty: 1}, unitPrice: 4000, quantity: 2, want: 0},
+		{name: "beli x gratis y tanpa konfigurasi", promo: Promotion{Type: PromotionTypeBuyXGetY}, unitPrice: 4000, quantity: 5, want: 0},
+		{name: "bundle", promo: Promotion{Type: PromotionTypeBundle, BundleQty: 3, BundlePrice: 10000}, unitPrice: 4000, quantity: 7, want: 4000},
+		{name: "harga bundle lebih mahal tidak jadi diskon negatif", promo: Promotion{Type: PromotionTypeBundle, BundleQty: 2, BundlePrice: 9000}, unitPrice: 4000, quantity: 4, want: 0},
+		{name: "harga bundle nol dibatasi subtotal", promo: Promotion{Type: PromotionTypeBundle, BundleQty: 2, BundlePrice: 0}, unitPrice: 4000, quantity: 3, want: 8000},
+		{name: "harga manual nol", promo: Promotion{Type: PromotionTypeFixed, Value: 1000}, unitPrice: 0, quantity: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.promo.DiscountFor(tt.unitPrice, tt.quantity)
+			if got != tt.want {
+				t.Errorf("DiscountFor(%d, %d) = %d, want %d", tt.unitPrice, tt.quantity, got, tt.want)
+			}
+			if got > tt.unitPrice*tt.quantity {
+				t.Errorf("diskon %d melebihi subtotal baris %d", got, tt.unitPrice*tt.quantity)
+			}
+		})
+	}
+}
+
+func TestPromotionAppliesTo(t *testing.T) {
+	product := Promotion{Type: PromotionTypePercentage, ProductID: 10}
+	category := Promotion{Type: PromotionTypeCategory, CategoryID: 3}
+	tests := []struct {
+		name                            string
+		promo                           Promotion
+		productID, parentID, categoryID int
+		want                            bool
+	}{
+		{name: "produk yang sama", promo: product, productID: 10, want: true},
+		{name: "produk lain", promo: product, productID: 11, want: false},
+		{name: "varian dari produk induk promo", promo: product, productID: 21, parentID: 10, want: true},
+		{name: "varian dari induk lain", promo: product, productID: 21, parentID: 12, want: false},
+		{name: "promo pada varian tidak berlaku untuk varian saudara", promo: Promotion{Type: PromotionTypeFixed, ProductID: 21}, productID: 22, parentID: 10, want: false},
+		{name: "kategori sama", promo: category, productID: 11, categoryID: 3, want: true},
+		{name: "kategori lain", promo: category, productID: 11, categoryID: 4, want: false},
+		{name: "produk tanpa kategori", promo: category, productID: 11, want: false},
+		{name: "promo kategori tidak cocok lewat product id", promo: Promotion{Type: PromotionTypeCategory, CategoryID: 3, ProductID: 10}, productID: 10, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promo.AppliesTo(tt.productID, tt.parentID, tt.categoryID); got != tt.want {
+				t.Errorf("AppliesTo(%d, %d, %d) = %v, want %v", tt.productID, tt.parentID, tt.categoryID, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusOrdered           = "ordered"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+
+	StockReferenceGoodsReceipt = "goods_receipt"
+)
+
+// PurchaseOrder - TotalCost = jumlah quantity * unit_cost semua item.
+// Items dan Receipts hanya diisi pada detail PO.
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	Note         string              `json:"note"`
+	TotalCost    int                 `json:"total_cost"`
+	CreatedBy    int                 `json:"created_by,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	OrderedAt    *time.Time          `json:"ordered_at,omitempty"`
+	ReceivedAt   *time.Time          `json:"received_at,omitempty"`
+	CancelledAt  *time.Time          `json:"cancelled_at,omitempty"`
+	Items        []PurchaseOrderItem `json:"items,omitempty"`
+	Receipts     []GoodsReceipt      `json:"receipts,omitempty"`
+}
+
+type PurchaseOrderItem struct {
+	ID              int    `json:"id"`
+	PurchaseOrderID int    `json:"purchase_order_id"`
+	ProductID       int    `json:"product_id"`
+	ProductName     string `json:"product_name"`
+	Quantity        int    `json:"quantity"`
+	ReceivedQty     int    `json:"received_qty"`
+	UnitCost        int    `json:"unit_cost"`
+	Subtotal        int    `json:"subtotal"`
+}
+
+type PurchaseOrderItemInput struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+	UnitCost  int `json:"unit_cost"`
+}
+
+// PurchaseOrderRequest - buat PO baru atau ubah PO yang masih draft (item diganti seluruhnya)
+type PurchaseOrderRequest struct {
+	SupplierID int                      `json:"supplier_id"`
+	Note       string                   `json:"note"`
+	Items      []PurchaseOrderItemInput `json:"items"`
+}
+
+type PurchaseOrderFilter struct {
+	SupplierID int
+	Status     string
+}
+
+type GoodsReceiptItemInput struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// GoodsReceiptRequest - barang yang diterima; boleh sebagian dari sisa quantity PO
+type GoodsReceiptRequest struct {
+	Note  string                  `json:"note"`
+	Items []GoodsReceiptItemInput `json:"items"`
+}
+
+type GoodsReceipt struct {
+	ID              int                `json:"id"`
+	PurchaseOrderID int                `json:"purchase_order_id"`
+	Note            string             `json:"note"`
+	UserID          int                `json:"user_id,omitempty"`
+	ReceivedAt      time.Time          `json:"received_at"`
+	Items           []GoodsReceiptItem `json:"items"`
+}
+
+type GoodsReceiptItem struct {
+	ID          int    `json:"id"`
+	ReceiptID   int    `json:"receipt_id"`
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+	UnitCost    int    `json:"unit_cost"`
+}
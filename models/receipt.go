@@ -0,0 +1,15 @@
+package models
+
+// StoreInfo - identitas toko yang dicetak di struk, diambil dari konfigurasi
+type StoreInfo struct {
+	Name    string
+	Address string
+	Phone   string
+	Footer  string
+}
+
+const (
+	ReceiptFormatText   = "text"
+	ReceiptFormatHTML   = "html"
+	ReceiptFormatESCPOS = "escpos"
+)
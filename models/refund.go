@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	TransactionStatusCompleted         = "completed"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusRefunded          = "refunded"
+	TransactionStatusVoided            = "voided"
+
+	RefundTypeVoid   = "void"
+	RefundTypeRefund = "refund"
+)
+
+type Refund struct {
+	ID            int            `json:"id"`
+	TransactionID int            `json:"transaction_id"`
+	Type          string         `json:"type"`
+	Reason        string         `json:"reason"`
+	TotalAmount   int            `json:"total_amount"`
+	UserID        int            `json:"user_id,omitempty"`
+	ApprovedBy    int            `json:"approved_by,omitempty"`
+	ShiftID       int            `json:"shift_id,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Details       []RefundDetail `json:"details"`
+}
+
+type RefundDetail struct {
+	ID                  int `json:"id"`
+	RefundID            int `json:"refund_id"`
+	TransactionDetailID int `json:"transaction_detail_id"`
+	ProductID           int `json:"product_id"`
+	Quantity            int `json:"quantity"`
+	Amount              int `json:"amount"`
+}
+
+type RefundItem struct {
+	TransactionDetailID int `json:"transaction_detail_id"`
+	Quantity            int `json:"quantity"`
+}
+
+type VoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+type RefundRequest struct {
+	Reason string       `json:"reason"`
+	Items  []RefundItem `json:"items"`
+}
@@ -1,9 +1,50 @@
 package models
 
+// DailySalesReport - GrossRevenue adalah nilai barang sebelum diskon.
+// TotalRevenue adalah uang masuk bersih (grand total transaksi termasuk pajak & service charge) dikurangi TotalRefund.
+// PenjualanBersih, TotalHPP, LabaKotor dan MarginPersen dihitung per item dari transaksi di periode tersebut:
+// nilai jual setelah diskon tanpa pajak & service charge, dikurangi item yang sudah di-refund.
 type DailySalesReport struct {
-	TotalRevenue    int            `json:"total_revenue"`
-	TotalTransaksi  int            `json:"total_transaksi"`
-	ProdukTerlaris  *TopProduct    `json:"produk_terlaris"`
+	GrossRevenue        int                     `json:"gross_revenue"`
+	TotalDiskon         int                     `json:"total_diskon"`
+	TotalPajak          int                     `json:"total_pajak"`
+	TotalServiceCharge  int                     `json:"total_service_charge"`
+	TotalRevenue        int                     `json:"total_revenue"`
+	TotalRefund         int                     `json:"total_refund"`
+	TotalTransaksi      int                     `json:"total_transaksi"`
+	PenjualanBersih     int                     `json:"penjualan_bersih"`
+	TotalHPP            int                     `json:"total_hpp"`
+	LabaKotor           int                     `json:"laba_kotor"`
+	MarginPersen        float64                 `json:"margin_persen"`
+	ProdukTerlaris      *TopProduct             `json:"produk_terlaris"`
+	PerMetodePembayaran []PaymentMethodSummary  `json:"per_metode_pembayaran"`
+	PerProduk           []ProductProfitSummary  `json:"per_produk"`
+	PerProdukInduk      []ProductProfitSummary  `json:"per_produk_induk"`
+	PerKategori         []CategoryProfitSummary `json:"per_kategori"`
+}
+
+// ProductProfitSummary - ProductID 0 berisi item dari produk yang sudah dihapus.
+// Di PerProduk satu baris per varian (ParentProductID terisi); di PerProdukInduk varian digabung ke induknya.
+type ProductProfitSummary struct {
+	ProductID       int     `json:"product_id"`
+	ParentProductID int     `json:"parent_product_id,omitempty"`
+	Nama            string  `json:"nama"`
+	QtyTerjual      int     `json:"qty_terjual"`
+	PenjualanBersih int     `json:"penjualan_bersih"`
+	TotalHPP        int     `json:"total_hpp"`
+	LabaKotor       int     `json:"laba_kotor"`
+	MarginPersen    float64 `json:"margin_persen"`
+}
+
+// CategoryProfitSummary - CategoryID 0 berisi produk tanpa kategori
+type CategoryProfitSummary struct {
+	CategoryID      int     `json:"category_id"`
+	Nama            string  `json:"nama"`
+	QtyTerjual      int     `json:"qty_terjual"`
+	PenjualanBersih int     `json:"penjualan_bersih"`
+	TotalHPP        int     `json:"total_hpp"`
+	LabaKotor       int     `json:"laba_kotor"`
+	MarginPersen    float64 `json:"margin_persen"`
 }
 
 type TopProduct struct {
@@ -11,6 +52,29 @@ type TopProduct struct {
 	QtyTerjual int    `json:"qty_terjual"`
 }
 
+// CashierSalesSummary - rekap penjualan per kasir; transaksi void tidak dihitung sama sekali.
+// TotalRefund adalah refund yang diproses kasir tersebut; TotalTunai adalah uang tunai
+// yang diterima kasir (setelah kembalian) dari transaksi yang tidak di-void.
+// CashierID 0 berisi transaksi lama yang belum tercatat kasirnya.
+type CashierSalesSummary struct {
+	CashierID          int    `json:"cashier_id"`
+	CashierName        string `json:"cashier_name"`
+	TotalTransaksi     int    `json:"total_transaksi"`
+	GrossRevenue       int    `json:"gross_revenue"`
+	TotalDiskon        int    `json:"total_diskon"`
+	TotalPajak         int    `json:"total_pajak"`
+	TotalServiceCharge int    `json:"total_service_charge"`
+	TotalRevenue       int    `json:"total_revenue"`
+	TotalRefund        int    `json:"total_refund"`
+	TotalTunai         int    `json:"total_tunai"`
+}
+
+type CashierSalesReport struct {
+	StartDate string                `json:"start_date"`
+	EndDate   string                `json:"end_date"`
+	PerKasir  []CashierSalesSummary `json:"per_kasir"`
+}
+
 type SalesReportFilter struct {
 	StartDate string
 	EndDate   string
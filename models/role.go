@@ -0,0 +1,91 @@
+package models
+
+import "slices"
+
+const (
+	RoleCashier    = "cashier"
+	RoleSupervisor = "supervisor"
+	RoleOwner      = "owner"
+)
+
+// Roles - role user yang dikenal, urut dari akses paling sempit
+var Roles = []string{
+	RoleCashier,
+	RoleSupervisor,
+	RoleOwner,
+}
+
+type Permission string
+
+const (
+	PermProductRead     Permission = "product:read"
+	PermProductWrite    Permission = "product:write"
+	PermProductDelete   Permission = "product:delete"
+	PermStockAdjust     Permission = "stock:adjust"
+	PermOpnameCount     Permission = "opname:count"
+	PermOpnameManage    Permission = "opname:manage"
+	PermNotification    Permission = "notification:read"
+	PermPurchase        Permission = "purchase:manage"
+	PermCategoryRead    Permission = "category:read"
+	PermCategoryWrite   Permission = "category:write"
+	PermPromotionRead   Permission = "promotion:read"
+	PermPromotionWrite  Permission = "promotion:write"
+	PermCheckout        Permission = "transaction:checkout"
+	PermPriceOverride   Permission = "transaction:price_override"
+	PermTransactionRead Permission = "transaction:read"
+	PermVoid            Permission = "transaction:void"
+	PermRefund          Permission = "transaction:refund"
+	PermShift           Permission = "shift:operate"
+	PermShiftRead       Permission = "shift:read"
+	PermReportRead      Permission = "report:read"
+	PermUserManage      Permission = "user:manage"
+)
+
+var cashierPermissions = []Permission{
+	PermProductRead,
+	PermCategoryRead,
+	PermPromotionRead,
+	PermCheckout,
+	PermTransactionRead,
+	PermShift,
+	PermOpnameCount,
+}
+
+var supervisorPermissions = append(slices.Clone(cashierPermissions),
+	PermProductWrite,
+	PermStockAdjust,
+	PermOpnameManage,
+	PermNotification,
+	PermPurchase,
+	PermCategoryWrite,
+	PermPromotionWrite,
+	PermPriceOverride,
+	PermVoid,
+	PermRefund,
+	PermShiftRead,
+	PermReportRead,
+)
+
+var ownerPermissions = append(slices.Clone(supervisorPermissions),
+	PermProductDelete,
+	PermUserManage,
+)
+
+// OverridablePermissions - izin kasir yang boleh disetujui supervisor lewat override; izin lain
+// (mis. user:manage) hanya bisa dipakai oleh user yang memang punya role-nya
+var OverridablePermissions = []Permission{
+	PermVoid,
+	PermRefund,
+	PermPriceOverride,
+}
+
+// RolePermissions - izin per role; supervisor mewarisi izin cashier, owner mewarisi izin supervisor
+var RolePermissions = map[string][]Permission{
+	RoleCashier:    cashierPermissions,
+	RoleSupervisor: supervisorPermissions,
+	RoleOwner:      ownerPermissions,
+}
+
+func HasPermission(role string, perm Permission) bool {
+	return slices.Contains(RolePermissions[role], perm)
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+
+	CashMovementPayIn  = "pay_in"
+	CashMovementPayout = "payout"
+)
+
+// Shift - ExpectedCash, CountedCash, dan OverShort (counted - expected) terisi saat shift ditutup
+type Shift struct {
+	ID            int            `json:"id"`
+	UserID        int            `json:"user_id"`
+	UserName      string         `json:"user_name"`
+	TerminalID    string         `json:"terminal_id,omitempty"`
+	OpeningFloat  int            `json:"opening_float"`
+	Status        string         `json:"status"`
+	OpenedAt      time.Time      `json:"opened_at"`
+	ClosedAt      *time.Time     `json:"closed_at,omitempty"`
+	CountedCash   *int           `json:"counted_cash,omitempty"`
+	ExpectedCash  *int           `json:"expected_cash,omitempty"`
+	OverShort     *int           `json:"over_short,omitempty"`
+	Note          string         `json:"note,omitempty"`
+	CashMovements []CashMovement `json:"cash_movements,omitempty"`
+}
+
+type CashMovement struct {
+	ID        int       `json:"id"`
+	ShiftID   int       `json:"shift_id"`
+	Type      string    `json:"type"`
+	Amount    int       `json:"amount"`
+	Reason    string    `json:"reason"`
+	UserID    int       `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OpenShiftRequest struct {
+	OpeningFloat int `json:"opening_float"`
+}
+
+type CloseShiftRequest struct {
+	CountedCash int    `json:"counted_cash"`
+	Note        string `json:"note"`
+}
+
+type CashMovementRequest struct {
+	Type   string `json:"type"`
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// ShiftCashSummary - perhitungan uang tunai di laci.
+// RefundTunai adalah porsi tunai dari void/refund yang diproses selama shift.
+type ShiftCashSummary struct {
+	OpeningFloat   int `json:"opening_float"`
+	PenjualanTunai int `json:"penjualan_tunai"`
+	RefundTunai    int `json:"refund_tunai"`
+	PayIn          int `json:"pay_in"`
+	Payout         int `json:"payout"`
+	KasDiharapkan  int `json:"kas_diharapkan"`
+}
+
+// ShiftReport - Z-report (laporan akhir shift)
+type ShiftReport struct {
+	Shift               *Shift                 `json:"shift"`
+	TotalTransaksi      int                    `json:"total_transaksi"`
+	GrossRevenue        int                    `json:"gross_revenue"`
+	TotalDiskon         int                    `json:"total_diskon"`
+	TotalPajak          int                    `json:"total_pajak"`
+	TotalServiceCharge  int                    `json:"total_service_charge"`
+	TotalPenjualan      int                    `json:"total_penjualan"`
+	TotalRefund         int                    `json:"total_refund"`
+	PerMetodePembayaran []PaymentMethodSummary `json:"per_metode_pembayaran"`
+	Kas                 ShiftCashSummary       `json:"kas"`
+	KasDihitung         *int                   `json:"kas_dihitung"`
+	Selisih             *int                   `json:"selisih"`
+}
+
+type ShiftFilter struct {
+	UserID    int
+	StartDate string
+	EndDate   string
+	Status    string
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	StockMovementInitial    = "initial"
+	StockMovementSale       = "sale"
+	StockMovementRefund     = "refund"
+	StockMovementPurchase   = "purchase"
+	StockMovementAdjustment = "adjustment"
+	StockMovementOpname     = "opname"
+	StockMovementTransfer   = "transfer"
+
+	StockReferenceTransaction = "transaction"
+	StockReferenceRefund      = "refund"
+)
+
+// ManualStockMovementTypes - jenis mutasi yang boleh dicatat lewat POST /api/produk/{id}/stok
+var ManualStockMovementTypes = []string{
+	StockMovementAdjustment,
+	StockMovementTransfer,
+}
+
+// StockMovement - satu baris ledger stok. Quantity bertanda: positif menambah, negatif mengurangi.
+// StockAfter adalah saldo produk setelah mutasi ini.
+type StockMovement struct {
+	ID            int       `json:"id"`
+	ProductID     int       `json:"product_id"`
+	Type          string    `json:"type"`
+	Quantity      int       `json:"quantity"`
+	StockAfter    int       `json:"stock_after"`
+	ReferenceType string    `json:"reference_type,omitempty"`
+	ReferenceID   int       `json:"reference_id,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	UserID        int       `json:"user_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// StockMovementRequest - mutasi stok manual; quantity bertanda (mis. -3 untuk barang rusak)
+type StockMovementRequest struct {
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+	Note     string `json:"note"`
+}
+
+type StockMovementFilter struct {
+	ProductID int
+	StartDate string
+	EndDate   string
+	Type      string
+	Limit     int
+	Offset    int
+}
+
+type StockMovementListResponse struct {
+	Data   []StockMovement `json:"data"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// TaxSettings - tarif default dari konfigurasi; tarif produk/kategori menimpa Rate
+type TaxSettings struct {
+	Rate              float64
+	Inclusive         bool
+	ServiceChargeRate float64
+}
+
+// TaxFor - pajak untuk nominal amount dengan tarif rate (persen).
+// Harga inklusif: pajak diambil dari dalam amount; eksklusif: pajak ditambahkan di atas amount.
+func (s TaxSettings) TaxFor(amount int, rate float64) int {
+	if rate <= 0 || amount <= 0 {
+		return 0
+	}
+	if s.Inclusive {
+		return amount - int(math.Round(float64(amount)/(1+rate/100)))
+	}
+	return int(math.Round(float64(amount) * rate / 100))
+}
+
+type TaxReport struct {
+	StartDate          string            `json:"start_date"`
+	EndDate            string            `json:"end_date"`
+	TotalDPP           int               `json:"total_dpp"`
+	TotalPajak         int               `json:"total_pajak"`
+	TotalServiceCharge int               `json:"total_service_charge"`
+	PerTarif           []TaxRateSummary  `json:"per_tarif"`
+	PerHari            []TaxDailySummary `json:"per_hari"`
+}
+
+type TaxRateSummary struct {
+	Tarif float64 `json:"tarif"`
+	DPP   int     `json:"dpp"`
+	Pajak int     `json:"pajak"`
+}
+
+type TaxDailySummary struct {
+	Tanggal       string `json:"tanggal"`
+	DPP           int    `json:"dpp"`
+	Pajak         int    `json:"pajak"`
+	ServiceCharge int    `json:"service_charge"`
+}
+
+// NullableRate - tarif pajak di body PATCH. Set membedakan field yang tidak dikirim (tidak diubah)
+// dari null (Value nil = kembali ikut tarif kategori / default).
+type NullableRate struct {
+	Set   bool
+	Value *float64
+}
+
+func (n *NullableRate) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	n.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
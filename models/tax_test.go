@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestTaxSettingsTaxFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		inclusive bool
+		amount    int
+		rate      float64
+		want      int
+	}{
+		{name: "eksklusif", amount: 10000, rate: 11, want: 1100},
+		{name: "eksklusif dibulatkan ke atas", amount: 1005, rate: 11, want: 111},
+		{name: "eksklusif dibulatkan ke bawah", amount: 1004, rate: 11, want: 110},
+		{name: "eksklusif setengah dibulatkan menjauhi nol", amount: 50, rate: 11, want: 6},
+		{name: "inklusif", inclusive: true, amount: 11100, rate: 11, want: 1100},
+		{name: "inklusif DPP dibulatkan ke bawah", inclusive: true, amount: 10000, rate: 11, want: 991},
+		{name: "inklusif DPP dibulatkan ke atas", inclusive: true, amount: 1000, rate: 11, want: 99},
+		{name: "inklusif tanpa galat float", inclusive: true, amount: 11200, rate: 12, want: 1200},
+		{name: "inklusif nominal kecil", inclusive: true, amount: 15, rate: 11, want: 1},
+		{name: "inklusif tarif desimal", inclusive: true, amount: 100000, rate: 2.5, want: 2439},
+		{name: "tarif nol", amount: 10000, rate: 0, want: 0},
+		{name: "nominal nol", inclusive: true, amount: 0, rate: 11, want: 0},
+		{name: "nominal negatif", amount: -1000, rate: 11, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := TaxSettings{Inclusive: tt.inclusive}
+			if got := s.TaxFor(tt.amount, tt.rate); got != tt.want {
+				t.Errorf("TaxFor(%d, %v) inclusive=%v = %d, want %d", tt.amount, tt.rate, tt.inclusive, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,27 +2,100 @@ package models
 
 import "time"
 
+// Transaction - TotalAmount adalah grand total yang dibayar pelanggan:
+// subtotal - diskon + service charge (+ pajak jika harga eksklusif pajak).
+// ApprovedBy adalah supervisor yang menyetujui override harga, jika ada.
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Details     []TransactionDetail `json:"details"`
+	ID                  int                 `json:"id"`
+	SubtotalAmount      int                 `json:"subtotal_amount"`
+	DiscountAmount      int                 `json:"discount_amount"`
+	TaxAmount           int                 `json:"tax_amount"`
+	ServiceChargeAmount int                 `json:"service_charge_amount"`
+	TaxInclusive        bool                `json:"tax_inclusive"`
+	TotalAmount         int                 `json:"total_amount"`
+	PaidAmount          int                 `json:"paid_amount"`
+	ChangeAmount        int                 `json:"change_amount"`
+	Status              string              `json:"status"`
+	CashierID           int                 `json:"cashier_id,omitempty"`
+	CashierName         string              `json:"cashier_name,omitempty"`
+	TerminalID          string              `json:"terminal_id,omitempty"`
+	ApprovedBy          int                 `json:"approved_by,omitempty"`
+	ShiftID             int                 `json:"shift_id,omitempty"`
+	CreatedAt           time.Time           `json:"created_at"`
+	Details             []TransactionDetail `json:"details"`
+	Payments            []Payment           `json:"payments,omitempty"`
+	Refunds             []Refund            `json:"refunds,omitempty"`
 }
 
+// TransactionDetail - Subtotal adalah nilai baris setelah diskon (unit_price * quantity - discount_amount).
+// UnitCost adalah snapshot HPP produk per unit saat checkout; ParentProductID terisi jika produknya varian.
 type TransactionDetail struct {
-	ID            int    `json:"id"`
-	TransactionID int    `json:"transaction_id"`
-	ProductID     int    `json:"product_id"`
-	ProductName   string `json:"product_name,omitempty"`
-	Quantity      int    `json:"quantity"`
-	Subtotal      int    `json:"subtotal"`
+	ID              int     `json:"id"`
+	TransactionID   int     `json:"transaction_id"`
+	ProductID       int     `json:"product_id"`
+	ParentProductID int     `json:"parent_product_id,omitempty"`
+	ProductName     string  `json:"product_name,omitempty"`
+	CategoryID      int     `json:"category_id,omitempty"`
+	CategoryName    string  `json:"category_name,omitempty"`
+	UnitPrice       int     `json:"unit_price"`
+	UnitCost        int     `json:"unit_cost"`
+	Quantity        int     `json:"quantity"`
+	Subtotal        int     `json:"subtotal"`
+	DiscountAmount  int     `json:"discount_amount"`
+	PromotionID     int     `json:"promotion_id,omitempty"`
+	TaxRate         float64 `json:"tax_rate"`
+	TaxAmount       int     `json:"tax_amount"`
 }
 
+// CheckoutItem - isi product_id atau barcode (hasil scan), tidak keduanya.
+// UnitPrice opsional untuk ubah harga saat transaksi (butuh izin
+// transaction:price_override atau override supervisor); kosong berarti harga produk
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID int    `json:"product_id,omitempty"`
+	Barcode   string `json:"barcode,omitempty"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice *int   `json:"unit_price,omitempty"`
 }
 
 type CheckoutRequest struct {
 	Items []CheckoutItem `json:"items"`
+	// Payments boleh kosong; transaksi dianggap dibayar tunai pas
+	Payments []PaymentInput `json:"payments,omitempty"`
+}
+
+// NewTransaction - data checkout yang sudah divalidasi service, siap disimpan repository
+type NewTransaction struct {
+	Items       []CheckoutItem
+	Payments    []PaymentInput
+	Promotions  []Promotion
+	Tax         TaxSettings
+	Actor       Actor
+	Idempotency *IdempotencyRecord
+}
+
+type TransactionFilter struct {
+	StartDate string
+	EndDate   string
+	MinAmount int
+	MaxAmount int
+	ProductID int
+	CashierID int
+	Limit     int
+	Offset    int
+}
+
+type TransactionListResponse struct {
+	Data   []Transaction `json:"data"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type IdempotencyRecord struct {
+	Key           string
+	CashierID     int
+	RequestHash   string
+	TransactionID int
+	Response      []byte
+	CreatedAt     time.Time
 }
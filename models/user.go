@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UserSession struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// AuthUser - identitas user dari access token, disimpan di context request
+type AuthUser struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID int    `json:"session_id"`
+}
+
+// Actor - siapa yang melakukan aksi: user login, terminal/perangkat (opsional),
+// dan supervisor yang menyetujui lewat override (0 jika tidak ada). OverrideID adalah jti override
+// untuk izin OverridePermission; ditandai terpakai di DB transaction aksi yang membutuhkan izin itu.
+type Actor struct {
+	UserID             int
+	TerminalID         string
+	ApprovedBy         int
+	OverrideID         string
+	OverridePermission Permission
+}
+
+// OverrideFor - actor apa adanya jika override-nya untuk izin perm; selain itu actor tanpa override,
+// supaya override untuk aksi lain tidak dihabiskan atau dicatat sebagai persetujuan
+func (a Actor) OverrideFor(perm Permission) Actor {
+	if a.OverrideID == "" || a.OverridePermission != perm {
+		a.ApprovedBy, a.OverrideID, a.OverridePermission = 0, "", ""
+	}
+	return a
+}
+
+// SupervisorOverride - persetujuan supervisor/owner untuk satu izin, sekali pakai, hanya untuk kasir
+// RequestedBy dan (untuk void/refund) transaksi TransactionID. Dibawa lewat header X-Supervisor-Token.
+type SupervisorOverride struct {
+	ID               string     `json:"-"`
+	ApproverID       int        `json:"approver_id"`
+	ApproverUsername string     `json:"approver_username"`
+	Permission       Permission `json:"permission"`
+	RequestedBy      int        `json:"requested_by"`
+	TransactionID    int        `json:"transaction_id,omitempty"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         *User  `json:"user"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+// UpdateUserRequest - field kosong/nil tidak diubah
+type UpdateUserRequest struct {
+	Name     *string `json:"name"`
+	Role     *string `json:"role"`
+	IsActive *bool   `json:"is_active"`
+	Password *string `json:"password"`
+}
+
+// OverrideRequest - kredensial supervisor yang diketik di terminal kasir untuk menyetujui satu aksi.
+// TransactionID wajib untuk void/refund dan harus kosong untuk override harga.
+type OverrideRequest struct {
+	Username      string     `json:"username"`
+	Password      string     `json:"password"`
+	Permission    Permission `json:"permission"`
+	TransactionID int        `json:"transaction_id,omitempty"`
+}
+
+type OverrideResponse struct {
+	OverrideToken string     `json:"override_token"`
+	Permission    Permission `json:"permission"`
+	TransactionID int        `json:"transaction_id,omitempty"`
+	ExpiresIn     int        `json:"expires_in"`
+	Approver      *User      `json:"approver"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestActorOverrideFor(t *testing.T) {
+	withOverride := Actor{UserID: 2, TerminalID: "T1", ApprovedBy: 1, OverrideID: "jti", OverridePermission: PermPriceOverride}
+	plain := Actor{UserID: 2, TerminalID: "T1"}
+	tests := []struct {
+		name  string
+		actor Actor
+		perm  Permission
+		want  Actor
+	}{
+		{name: "izin sama", actor: withOverride, perm: PermPriceOverride, want: withOverride},
+		{name: "izin lain", actor: withOverride, perm: PermVoid, want: plain},
+		{name: "tanpa override", actor: plain, perm: PermPriceOverride, want: plain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.actor.OverrideFor(tt.perm); got != tt.want {
+				t.Errorf("OverrideFor(%s) = %+v, want %+v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,17 @@ import (
 	"database/sql"
 	"errors"
 	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCategoryNotFound - kategori dengan ID tersebut tidak ada
+	ErrCategoryNotFound    = errors.New("kategori tidak ditemukan")
+	ErrCategoryArchived    = errors.New("kategori sudah diarsipkan")
+	ErrCategoryNotArchived = errors.New("kategori tidak diarsipkan")
+	// ErrCategoryInUse - kategori masih dipakai produk (termasuk yang diarsipkan) atau promo; arsipkan saja
+	ErrCategoryInUse = errors.New("kategori masih dipakai produk / promo, arsipkan saja")
 )
 
 type CategoryRepository struct {
@@ -14,8 +25,12 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := "SELECT id, name, description FROM categories"
+// GetAll - kategori aktif, atau hanya yang diarsipkan jika archived true
+func (repo *CategoryRepository) GetAll(archived bool) ([]models.Category, error) {
+	query := "SELECT id, name, description, tax_rate, version, deleted_at FROM categories WHERE deleted_at IS NULL"
+	if archived {
+		query = "SELECT id, name, description, tax_rate, version, deleted_at FROM categories WHERE deleted_at IS NOT NULL"
+	}
 	rows, err := repo.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -25,7 +40,7 @@ func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
 	categories := make([]models.Category, 0)
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.Name, &c.Description)
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.TaxRate, &c.Version, &c.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -36,18 +51,18 @@ func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
 }
 
 func (repo *CategoryRepository) Create(category *models.Category) error {
-	query := "INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id"
-	err := repo.db.QueryRow(query, category.Name, category.Description).Scan(&category.ID)
+	query := "INSERT INTO categories (name, description, tax_rate) VALUES ($1, $2, $3) RETURNING id, version"
+	err := repo.db.QueryRow(query, category.Name, category.Description, category.TaxRate).Scan(&category.ID, &category.Version)
 	return err
 }
 
 func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	query := "SELECT id, name, description FROM categories WHERE id = $1"
+	query := "SELECT id, name, description, tax_rate, version, deleted_at FROM categories WHERE id = $1"
 
 	var c models.Category
-	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description)
+	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description, &c.TaxRate, &c.Version, &c.DeletedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("kategori tidak ditemukan")
+		return nil, ErrCategoryNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -56,27 +71,69 @@ func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
 	return &c, nil
 }
 
+// Update - jika category.Version diisi, harus sama dengan versi di database (optimistic locking);
+// versi baru ditulis balik ke category.Version
 func (repo *CategoryRepository) Update(category *models.Category) error {
-	query := "UPDATE categories SET name = $1, description = $2 WHERE id = $3"
-	result, err := repo.db.Exec(query, category.Name, category.Description, category.ID)
+	query := `UPDATE categories SET name = $1, description = $2, tax_rate = $3, version = version + 1
+		WHERE id = $4 AND ($5::int = 0 OR version = $5) RETURNING version`
+	err := repo.db.QueryRow(query, category.Name, category.Description, category.TaxRate, category.ID, category.Version).
+		Scan(&category.Version)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1)", category.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrVersionConflict
+		}
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+// Delete - hapus permanen; hanya untuk kategori yang tidak dipakai produk (termasuk yang diarsipkan) maupun promo
+func (repo *CategoryRepository) Delete(id int) error {
+	query := `DELETE FROM categories WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM products WHERE category_id = $1)
+		AND NOT EXISTS (SELECT 1 FROM promotions WHERE category_id = $1)`
+	result, err := repo.db.Exec(query, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrCategoryInUse
+	}
 	if err != nil {
 		return err
 	}
-
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
 	if rows == 0 {
-		return errors.New("kategori tidak ditemukan")
+		if _, err := repo.GetByID(id); err != nil {
+			return err
+		}
+		return ErrCategoryInUse
 	}
 
 	return nil
 }
 
-func (repo *CategoryRepository) Delete(id int) error {
-	query := "DELETE FROM categories WHERE id = $1"
+// Archive - arsipkan kategori; produknya tetap memakai kategori ini (termasuk tarif pajaknya)
+func (repo *CategoryRepository) Archive(id int) error {
+	return repo.setDeletedAt(id, true)
+}
+
+// Restore - pulihkan kategori yang diarsipkan
+func (repo *CategoryRepository) Restore(id int) error {
+	return repo.setDeletedAt(id, false)
+}
+
+func (repo *CategoryRepository) setDeletedAt(id int, archive bool) error {
+	query := "UPDATE categories SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	if !archive {
+		query = "UPDATE categories SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL"
+	}
 	result, err := repo.db.Exec(query, id)
 	if err != nil {
 		return err
@@ -85,10 +142,16 @@ func (repo *CategoryRepository) Delete(id int) error {
 	if err != nil {
 		return err
 	}
-
-	if rows == 0 {
-		return errors.New("kategori tidak ditemukan")
+	if rows > 0 {
+		return nil
 	}
 
-	return nil
+	// Tidak ada yang berubah: kategori tidak ada, atau statusnya sudah sesuai
+	if _, err := repo.GetByID(id); err != nil {
+		return err
+	}
+	if archive {
+		return ErrCategoryArchived
+	}
+	return ErrCategoryNotArchived
 }
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"kasir-api/models"
+	"time"
+)
+
+// ErrNotificationNotFound - notifikasi dengan ID tersebut tidak ada
+var ErrNotificationNotFound = errors.New("notifikasi tidak ditemukan")
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// insertNotification - tulis notifikasi di DB transaction pemanggil supaya ikut rollback bila gagal
+func insertNotification(tx *sql.Tx, notificationType, message string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO notifications (type, message, payload) VALUES ($1, $2, $3)",
+		notificationType, message, data)
+	return err
+}
+
+// GetAll - notifikasi terbaru lebih dulu
+func (repo *NotificationRepository) GetAll(filter models.NotificationFilter) ([]models.Notification, error) {
+	query := "SELECT id, type, message, payload, read_at, created_at FROM notifications"
+	if filter.UnreadOnly {
+		query += " WHERE read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT $1"
+
+	rows, err := repo.db.Query(query, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var n models.Notification
+		var payload []byte
+		if err := rows.Scan(&n.ID, &n.Type, &n.Message, &payload, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.Payload = payload
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// MarkRead - tandai notifikasi sudah dibaca; idempotent untuk notifikasi yang sudah dibaca
+func (repo *NotificationRepository) MarkRead(id int64) error {
+	result, err := repo.db.Exec("UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// GetUndelivered - notifikasi yang belum terkirim ke webhook, dibuat setelah since,
+// dan belum mencapai batas percobaan. Urut dari yang terlama.
+func (repo *NotificationRepository) GetUndelivered(since time.Time, maxAttempts, limit int) ([]models.Notification, error) {
+	rows, err := repo.db.Query(
+		`SELECT id, type, message, payload, read_at, created_at FROM notifications
+		WHERE delivered_at IS NULL AND created_at >= $1 AND delivery_attempts < $2
+		ORDER BY id LIMIT $3`,
+		since, maxAttempts, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var n models.Notification
+		var payload []byte
+		if err := rows.Scan(&n.ID, &n.Type, &n.Message, &payload, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.Payload = payload
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (repo *NotificationRepository) MarkDelivered(id int64) error {
+	_, err := repo.db.Exec(
+		"UPDATE notifications SET delivered_at = CURRENT_TIMESTAMP, delivery_attempts = delivery_attempts + 1, last_error = '' WHERE id = $1",
+		id,
+	)
+	return err
+}
+
+func (repo *NotificationRepository) MarkDeliveryFailed(id int64, deliveryErr string) error {
+	_, err := repo.db.Exec(
+		"UPDATE notifications SET delivery_attempts = delivery_attempts + 1, last_error = $1 WHERE id = $2",
+		deliveryErr, id,
+	)
+	return err
+}
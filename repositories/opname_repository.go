@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"sort"
+)
+
+var (
+	ErrOpnameNotFound = errors.New("sesi opname tidak ditemukan")
+	ErrOpnameNotOpen  = errors.New("sesi opname sudah tidak open")
+)
+
+type OpnameRepository struct {
+	db *sql.DB
+}
+
+func NewOpnameRepository(db *sql.DB) *OpnameRepository {
+	return &OpnameRepository{db: db}
+}
+
+const opnameSessionColumns = `s.id, s.name, s.note, s.status, s.created_by, s.created_at, s.finalized_by, s.finalized_at, s.reason,
+	(SELECT COUNT(DISTINCT product_id) FROM opname_counts WHERE session_id = s.id)`
+
+func scanOpnameSession(scanner interface{ Scan(...interface{}) error }) (*models.OpnameSession, error) {
+	var o models.OpnameSession
+	var createdBy, finalizedBy sql.NullInt64
+	var finalizedAt sql.NullTime
+	err := scanner.Scan(&o.ID, &o.Name, &o.Note, &o.Status, &createdBy, &o.CreatedAt, &finalizedBy, &finalizedAt, &o.Reason,
+		&o.JumlahProduk)
+	if err != nil {
+		return nil, err
+	}
+	o.CreatedBy = int(createdBy.Int64)
+	o.FinalizedBy = int(finalizedBy.Int64)
+	if finalizedAt.Valid {
+		o.FinalizedAt = &finalizedAt.Time
+	}
+	return &o, nil
+}
+
+func (repo *OpnameRepository) Create(session *models.OpnameSession) error {
+	return repo.db.QueryRow(
+		"INSERT INTO opname_sessions (name, note, created_by) VALUES ($1, $2, $3) RETURNING id, status, created_at",
+		session.Name, session.Note, nullableID(session.CreatedBy),
+	).Scan(&session.ID, &session.Status, &session.CreatedAt)
+}
+
+func (repo *OpnameRepository) GetAll() ([]models.OpnameSession, error) {
+	rows, err := repo.db.Query("SELECT " + opnameSessionColumns + " FROM opname_sessions s ORDER BY s.created_at DESC, s.id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.OpnameSession, 0)
+	for rows.Next() {
+		o, err := scanOpnameSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *o)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (repo *OpnameRepository) GetByID(id int) (*models.OpnameSession, error) {
+	o, err := scanOpnameSession(repo.db.QueryRow("SELECT "+opnameSessionColumns+" FROM opname_sessions s WHERE s.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrOpnameNotFound
+	}
+	return o, err
+}
+
+// SubmitCounts - simpan hasil hitung dari satu perangkat; hitungan perangkat yang sama untuk produk
+// yang sama menimpa hitungan sebelumnya. Produk induk yang punya varian dan produk arsip ditolak.
+func (repo *OpnameRepository) SubmitCounts(sessionID int, deviceID string, userID int, items []models.OpnameCountInput) ([]models.OpnameCount, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// FOR SHARE: hitungan tidak bisa masuk bersamaan dengan finalisasi
+	var status string
+	err = tx.QueryRow("SELECT status FROM opname_sessions WHERE id = $1 FOR SHARE", sessionID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrOpnameNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != models.OpnameStatusOpen {
+		return nil, ErrOpnameNotOpen
+	}
+
+	counts := make([]models.OpnameCount, 0, len(items))
+	for _, item := range items {
+		// Induk tidak punya stok sendiri dan produk arsip tidak dijual; hitungan seperti ini
+		// akan menggagalkan finalisasi seluruh sesi, jadi ditolak sejak awal
+		var archived, hasVariants bool
+		err := tx.QueryRow("SELECT deleted_at IS NOT NULL, EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id) FROM products p WHERE p.id = $1",
+			item.ProductID).Scan(&archived, &hasVariants)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("product id %d not found", item.ProductID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if archived {
+			return nil, fmt.Errorf("product id %d sudah diarsipkan dan tidak bisa dihitung", item.ProductID)
+		}
+		if hasVariants {
+			return nil, fmt.Errorf("product id %d punya varian, hitung per varian", item.ProductID)
+		}
+
+		c := models.OpnameCount{
+			SessionID:  sessionID,
+			ProductID:  item.ProductID,
+			DeviceID:   deviceID,
+			CountedQty: item.CountedQty,
+			UserID:     userID,
+		}
+		// Stok sistem dicatat saat hitungan masuk, bukan saat finalisasi
+		err = tx.QueryRow(
+			`INSERT INTO opname_counts (session_id, product_id, device_id, counted_qty, user_id, system_qty)
+			SELECT $1, p.id, $3, $4, $5, p.stock FROM products p WHERE p.id = $2
+			ON CONFLICT (session_id, product_id, device_id)
+			DO UPDATE SET counted_qty = EXCLUDED.counted_qty, user_id = EXCLUDED.user_id,
+				system_qty = EXCLUDED.system_qty, counted_at = CURRENT_TIMESTAMP
+			RETURNING id, system_qty, counted_at`,
+			sessionID, item.ProductID, deviceID, item.CountedQty, nullableID(userID),
+		).Scan(&c.ID, &c.SystemQty, &c.CountedAt)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// opnameCountTotals - hitungan per produk dalam satu sesi ($1). Hitungan beberapa perangkat
+// dijumlahkan; stok sistemnya diambil dari hitungan yang paling awal masuk, yaitu saat produk
+// mulai dihitung.
+const opnameCountTotals = `SELECT product_id, SUM(counted_qty) AS counted,
+		(ARRAY_AGG(system_qty ORDER BY counted_at, id))[1] AS system_qty
+	FROM opname_counts WHERE session_id = $1 GROUP BY product_id`
+
+// GetVariance - selisih per produk; sesi final membaca hasil yang dibekukan, sesi open dihitung
+// terhadap stok sistem saat produk dihitung
+func (repo *OpnameRepository) GetVariance(session *models.OpnameSession) ([]models.OpnameVarianceItem, error) {
+	query := `SELECT p.id, p.name, p.price, c.system_qty, c.counted
+		FROM (` + opnameCountTotals + `) c
+		JOIN products p ON p.id = c.product_id
+		ORDER BY p.id`
+	if session.Status == models.OpnameStatusFinalized {
+		query = `SELECT product_id, product_name, unit_price, system_qty, counted_qty
+			FROM opname_results WHERE session_id = $1 ORDER BY product_id`
+	}
+
+	rows, err := repo.db.Query(query, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.OpnameVarianceItem, 0)
+	for rows.Next() {
+		var v models.OpnameVarianceItem
+		if err := rows.Scan(&v.ProductID, &v.ProductName, &v.UnitPrice, &v.SystemQty, &v.CountedQty); err != nil {
+			return nil, err
+		}
+		v.Selisih = v.CountedQty - v.SystemQty
+		v.NilaiSelisih = v.Selisih * v.UnitPrice
+		items = append(items, v)
+	}
+
+	return items, rows.Err()
+}
+
+// Finalize - bekukan selisih dan sesuaikan stok setiap produk yang dihitung lewat ledger stok
+// (mutasi "opname"), semuanya dalam satu DB transaction. Penyesuaian = counted - system_qty saat
+// hitung, sehingga mutasi yang terjadi setelah produk dihitung tetap dipertahankan.
+func (repo *OpnameRepository) Finalize(sessionID, userID int, reason string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM opname_sessions WHERE id = $1 FOR UPDATE", sessionID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrOpnameNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OpnameStatusOpen {
+		return ErrOpnameNotOpen
+	}
+
+	rows, err := tx.Query(opnameCountTotals+" ORDER BY product_id", sessionID)
+	if err != nil {
+		return err
+	}
+	counted := make(map[int]int)
+	systemQty := make(map[int]int)
+	productIDs := make([]int64, 0)
+	for rows.Next() {
+		var productID, qty, system int
+		if err := rows.Scan(&productID, &qty, &system); err != nil {
+			rows.Close()
+			return err
+		}
+		counted[productID] = qty
+		systemQty[productID] = system
+		productIDs = append(productIDs, int64(productID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(productIDs) == 0 {
+		return errors.New("belum ada produk yang dihitung")
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	// Urutan lock sama dengan checkout supaya tidak deadlock
+	products, err := lockProducts(tx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range productIDs {
+		p, ok := products[int(id)]
+		if !ok {
+			return fmt.Errorf("product id %d not found", id)
+		}
+		qty, system := counted[int(id)], systemQty[int(id)]
+
+		_, err = tx.Exec(
+			`INSERT INTO opname_results (session_id, product_id, product_name, unit_price, system_qty, counted_qty)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			sessionID, id, p.name, p.price, system, qty,
+		)
+		if err != nil {
+			return err
+		}
+
+		if delta := qty - system; delta != 0 {
+			err = applyStockMovement(tx, &models.StockMovement{
+				ProductID:     int(id),
+				Type:          models.StockMovementOpname,
+				Quantity:      delta,
+				ReferenceType: models.StockReferenceOpname,
+				ReferenceID:   sessionID,
+				Note:          reason,
+				UserID:        userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE opname_sessions SET status = $1, finalized_by = $2, finalized_at = CURRENT_TIMESTAMP, reason = $3 WHERE id = $4",
+		models.OpnameStatusFinalized, nullableID(userID), reason, sessionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *OpnameRepository) Cancel(sessionID int) error {
+	result, err := repo.db.Exec("UPDATE opname_sessions SET status = $1 WHERE id = $2 AND status = $3",
+		models.OpnameStatusCancelled, sessionID, models.OpnameStatusOpen)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := repo.GetByID(sessionID); err != nil {
+			return err
+		}
+		return ErrOpnameNotOpen
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"kasir-api/models"
+	"testing"
+)
+
+// TestOpnameFinalizeKeepsSalesAfterCount - penjualan yang terjadi setelah produk dihitung tidak
+// boleh dihapus oleh finalisasi; yang disesuaikan hanya selisih hitung vs stok saat dihitung.
+func TestOpnameFinalizeKeepsSalesAfterCount(t *testing.T) {
+	db := openTestDB(t)
+	opnames := NewOpnameRepository(db)
+	transactions := NewTransactionRepository(db)
+
+	var productID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Produk Opname', 1000, 20) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	session := &models.OpnameSession{Name: "Opname test"}
+	if err := opnames.Create(session); err != nil {
+		t.Fatal(err)
+	}
+	// Fisik 18 dari stok sistem 20: hilang 2
+	counts, err := opnames.SubmitCounts(session.ID, "rak", 0, []models.OpnameCountInput{{ProductID: productID, CountedQty: 18}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[0].SystemQty != 20 {
+		t.Fatalf("system_qty = %d, want 20", counts[0].SystemQty)
+	}
+
+	// Terjual 3 setelah dihitung, sebelum finalisasi
+	if _, err := transactions.CreateTransaction(models.NewTransaction{Items: []models.CheckoutItem{{ProductID: productID, Quantity: 3}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	open, err := opnames.GetByID(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := opnames.GetVariance(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].SystemQty != 20 || items[0].Selisih != -2 {
+		t.Fatalf("selisih sesi open = %+v, want system_qty 20 selisih -2", items)
+	}
+
+	if err := opnames.Finalize(session.ID, 0, "hilang"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stock int
+	if err := db.QueryRow("SELECT stock FROM products WHERE id = $1", productID).Scan(&stock); err != nil {
+		t.Fatal(err)
+	}
+	if stock != 15 {
+		t.Errorf("stok setelah finalisasi = %d, want 15 (18 dihitung - 3 terjual)", stock)
+	}
+
+	final, err := opnames.GetByID(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err = opnames.GetVariance(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].SystemQty != 20 || items[0].CountedQty != 18 {
+		t.Errorf("selisih final = %+v, want system_qty 20 counted 18", items)
+	}
+}
+
+// TestOpnameSubmitCountsRejectsParentAndArchived - induk bervarian dan produk arsip tidak bisa dihitung,
+// supaya finalisasi sesi tidak gagal belakangan karena hitungan tersebut
+func TestOpnameSubmitCountsRejectsParentAndArchived(t *testing.T) {
+	db := openTestDB(t)
+	opnames := NewOpnameRepository(db)
+
+	var parentID, variantID, archivedID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Kaos', 50000, 0) RETURNING id").Scan(&parentID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("INSERT INTO products (name, variant_name, parent_id, price, stock) VALUES ('Kaos - M', 'M', $1, 50000, 4) RETURNING id",
+		parentID).Scan(&variantID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("INSERT INTO products (name, price, stock, deleted_at) VALUES ('Lama', 1000, 3, CURRENT_TIMESTAMP) RETURNING id").Scan(&archivedID); err != nil {
+		t.Fatal(err)
+	}
+
+	session := &models.OpnameSession{Name: "Opname varian"}
+	if err := opnames.Create(session); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name      string
+		productID int
+	}{{"induk bervarian", parentID}, {"produk arsip", archivedID}, {"produk tidak ada", 999999}} {
+		_, err := opnames.SubmitCounts(session.ID, "", 0, []models.OpnameCountInput{
+			{ProductID: variantID, CountedQty: 4}, {ProductID: tt.productID, CountedQty: 1},
+		})
+		if err == nil {
+			t.Errorf("%s: hitungan diterima, want error", tt.name)
+		}
+	}
+
+	// Hitungan yang ditolak ikut membatalkan hitungan lain dalam request yang sama
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM opname_counts WHERE session_id = $1", session.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("%d hitungan tersimpan, want 0", stored)
+	}
+
+	if _, err := opnames.SubmitCounts(session.ID, "", 0, []models.OpnameCountInput{{ProductID: variantID, CountedQty: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := opnames.Finalize(session.ID, 0, "cocok"); err != nil {
+		t.Errorf("finalisasi: %v", err)
+	}
+}
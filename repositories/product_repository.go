@@ -3,7 +3,31 @@ package repositories
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"kasir-api/models"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrProductNotFound - produk dengan ID / barcode tersebut tidak ada
+	ErrProductNotFound = errors.New("produk tidak ditemukan")
+	ErrDuplicateSKU    = errors.New("sku sudah dipakai produk lain")
+	// ErrDuplicateBarcode - satu barcode hanya boleh dimiliki satu produk
+	ErrDuplicateBarcode = errors.New("barcode sudah dipakai produk lain")
+	// ErrProductHasVariants - stok / penjualan produk induk harus lewat variannya
+	ErrProductHasVariants = errors.New("produk punya varian; gunakan varian produk")
+	ErrDuplicateVariant   = errors.New("nama varian sudah dipakai di produk ini")
+	// ErrVersionConflict - produk / kategori sudah diubah orang lain sejak dibaca (optimistic locking)
+	ErrVersionConflict = errors.New("data sudah diubah pengguna lain, muat ulang lalu coba lagi")
+	// ErrProductArchived - produk diarsipkan; pulihkan dulu sebelum diedit / dijual
+	ErrProductArchived    = errors.New("produk sudah diarsipkan")
+	ErrProductNotArchived = errors.New("produk tidak diarsipkan")
+	// ErrProductInUse - produk sudah punya riwayat (transaksi, pembelian, opname, promo); arsipkan saja
+	ErrProductInUse = errors.New("produk sudah dipakai di transaksi / pembelian / opname / promo, arsipkan saja")
 )
 
 type ProductRepository struct {
@@ -14,18 +38,47 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (repo *ProductRepository) GetAll(nameFilter string) ([]models.Product, error) {
-	query := `SELECT products.id, products.name, products.price, products.stock, 
-			  products.category_id, categories.name AS category_name 
-			  FROM products 
-			  LEFT JOIN categories ON products.category_id = categories.id`
+const productColumns = `products.id, products.name, COALESCE(products.parent_id, 0), COALESCE(products.variant_name, ''),
+	COALESCE(products.sku, ''), products.price, products.cost_price,
+	products.stock, products.min_stock, products.category_id, categories.name AS category_name, products.tax_rate,
+	COALESCE((SELECT ARRAY_AGG(pb.code ORDER BY pb.id) FROM product_barcodes pb WHERE pb.product_id = products.id), '{}'),
+	products.created_at, products.version, products.deleted_at`
 
-	args := []interface{}{}
-	if nameFilter != "" {
-		query += " WHERE products.name ILIKE $1"
-		args = append(args, "%"+nameFilter+"%")
+// productSortColumns - kolom sort yang diizinkan untuk daftar produk (whitelist, jangan pernah
+// menyisipkan input user langsung ke ORDER BY)
+var productSortColumns = map[string]string{
+	"name":       "products.name",
+	"price":      "products.price",
+	"stock":      "products.stock",
+	"created_at": "products.created_at",
+}
+
+func scanProduct(scanner interface{ Scan(...interface{}) error }) (*models.Product, error) {
+	var p models.Product
+	var categoryID sql.NullInt64
+	var categoryName sql.NullString
+	var barcodes pq.StringArray
+	var createdAt sql.NullTime
+	err := scanner.Scan(&p.ID, &p.Name, &p.ParentID, &p.VariantName, &p.SKU, &p.Price, &p.CostPrice, &p.Stock, &p.MinStock, &categoryID, &categoryName,
+		&p.TaxRate, &barcodes, &createdAt, &p.Version, &p.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if categoryID.Valid {
+		p.CategoryID = int(categoryID.Int64)
 	}
+	if categoryName.Valid {
+		p.CategoryName = categoryName.String
+	}
+	p.Barcodes = []string(barcodes)
+	if createdAt.Valid {
+		p.CreatedAt = createdAt.Time
+	}
+	return &p, nil
+}
 
+// queryProducts - jalankan query yang memilih productColumns dan kumpulkan hasilnya
+func (repo *ProductRepository) queryProducts(query string, args ...interface{}) ([]models.Product, error) {
 	rows, err := repo.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -34,92 +87,639 @@ func (repo *ProductRepository) GetAll(nameFilter string) ([]models.Product, erro
 
 	products := make([]models.Product, 0)
 	for rows.Next() {
-		var p models.Product
-		var categoryID sql.NullInt64
-		var categoryName sql.NullString
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryID, &categoryName)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, err
 		}
-		if categoryID.Valid {
-			p.CategoryID = int(categoryID.Int64)
-		}
-		if categoryName.Valid {
-			p.CategoryName = categoryName.String
-		}
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
-	return products, nil
+	return products, rows.Err()
 }
 
-func (repo *ProductRepository) Create(product *models.Product) error {
-	query := "INSERT INTO products (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id"
-	err := repo.db.QueryRow(query, product.Name, product.Price, product.Stock, product.CategoryID).Scan(&product.ID)
-	return err
+// GetAll - ambil daftar produk sesuai filter dan urutan, beserta total data untuk pagination.
+// Sort & Order diasumsikan sudah divalidasi service; id selalu jadi tie-breaker supaya halaman stabil.
+func (repo *ProductRepository) GetAll(filter models.ProductFilter) ([]models.Product, int, error) {
+	conditions := []string{"products.deleted_at IS NULL"}
+	if filter.Archived {
+		conditions[0] = "products.deleted_at IS NOT NULL"
+	}
+	args := []interface{}{}
+
+	if filter.Name != "" {
+		args = append(args, "%"+filter.Name+"%")
+		conditions = append(conditions, fmt.Sprintf("products.name ILIKE $%d", len(args)))
+	}
+	if filter.CategoryID > 0 {
+		args = append(args, filter.CategoryID)
+		conditions = append(conditions, fmt.Sprintf("products.category_id = $%d", len(args)))
+	}
+	if filter.MinPrice > 0 {
+		args = append(args, filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("products.price >= $%d", len(args)))
+	}
+	if filter.MaxPrice > 0 {
+		args = append(args, filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("products.price <= $%d", len(args)))
+	}
+	if filter.InStock {
+		conditions = append(conditions, "products.stock > 0")
+	}
+	if filter.LowStock {
+		conditions = append(conditions, "products.min_stock > 0 AND products.stock <= products.min_stock")
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM products"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn, ok := productSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = productSortColumns["name"]
+	}
+	direction := "ASC"
+	if filter.Order == "desc" {
+		direction = "DESC"
+	}
+
+	query := "SELECT " + productColumns + `
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id` + where +
+		fmt.Sprintf(" ORDER BY %s %s, products.id %s LIMIT $%d OFFSET $%d", sortColumn, direction, direction, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	products, err := repo.queryProducts(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+// GetLowStock - produk yang dipantau (min_stock > 0) dengan stok di bawah atau sama dengan min_stock,
+// paling kritis lebih dulu
+func (repo *ProductRepository) GetLowStock() ([]models.Product, error) {
+	query := "SELECT " + productColumns + ` 
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.min_stock > 0 AND products.stock <= products.min_stock AND products.deleted_at IS NULL 
+			  ORDER BY products.stock - products.min_stock, products.id`
+
+	return repo.queryProducts(query)
 }
 
-// GetByID - ambil produk by ID
+// likeEscaper - escape karakter wildcard LIKE dari input user
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// prefixTSQuery - ubah input pencarian jadi tsquery prefix ("indo goreng" -> "indo:* & goreng:*")
+// supaya cocok untuk type-ahead. Hanya huruf/angka yang dipakai sehingga aman dari sintaks tsquery.
+func prefixTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Search - cari produk by nama, SKU, barcode, atau nama kategori dengan full-text + trigram, terurut relevansi.
+// Kandidat dikumpulkan lewat UNION supaya tiap cabang bisa memakai index-nya sendiri, lalu diberi skor:
+// SKU/barcode persis > cocok full-text > kemiripan nama (toleran typo) > kemiripan nama kategori.
+func (repo *ProductRepository) Search(query string, limit int) ([]models.Product, error) {
+	sqlQuery := `WITH candidates AS (
+				SELECT id FROM products WHERE search_vector @@ to_tsquery('simple', $2)
+				UNION
+				SELECT id FROM products WHERE $1::text <% name
+				UNION
+				SELECT id FROM products WHERE sku ILIKE $3
+				UNION
+				SELECT product_id FROM product_barcodes WHERE code LIKE $3
+				UNION
+				SELECT products.id FROM products JOIN categories ON categories.id = products.category_id
+				WHERE $1::text <% categories.name
+			  )
+			  SELECT ` + productColumns + ` 
+			  FROM candidates 
+			  JOIN products ON products.id = candidates.id 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.deleted_at IS NULL 
+			  ORDER BY 
+				CASE WHEN products.sku = $1::text
+					OR EXISTS (SELECT 1 FROM product_barcodes pb WHERE pb.product_id = products.id AND pb.code = $1::text) THEN 2
+				WHEN products.search_vector @@ to_tsquery('simple', $2) THEN 1
+				ELSE 0 END
+				+ word_similarity($1::text, products.name)
+				+ 0.5 * word_similarity($1::text, COALESCE(categories.name, '')) DESC,
+				products.name, products.id 
+			  LIMIT $4`
+
+	return repo.queryProducts(sqlQuery, query, prefixTSQuery(query), likeEscaper.Replace(query)+"%", limit)
+}
+
+// Create - simpan produk baru; stok awal dicatat sebagai mutasi "initial" di ledger.
+// Untuk varian (ParentID > 0), produk induk dikunci dan stoknya harus 0 supaya stok hanya ada di varian.
+func (repo *ProductRepository) Create(product *models.Product, userID int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if product.ParentID > 0 {
+		if err := lockVariantParent(tx, product.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := insertProduct(tx, product, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID - ambil produk by ID (termasuk yang diarsipkan) beserta varian aktifnya (jika produk induk)
 func (repo *ProductRepository) GetByID(id int) (*models.Product, error) {
-	query := `SELECT products.id, products.name, products.price, products.stock, 
-			  products.category_id, categories.name AS category_name 
+	query := "SELECT " + productColumns + ` 
 			  FROM products 
 			  LEFT JOIN categories ON products.category_id = categories.id 
 			  WHERE products.id = $1`
 
-	var p models.Product
-	var categoryID sql.NullInt64
-	var categoryName sql.NullString
-	err := repo.db.QueryRow(query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryID, &categoryName)
+	p, err := scanProduct(repo.db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
-		return nil, errors.New("produk tidak ditemukan")
+		return nil, ErrProductNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	if categoryID.Valid {
-		p.CategoryID = int(categoryID.Int64)
+
+	if p.ParentID == 0 {
+		p.Variants, err = repo.queryProducts("SELECT "+productColumns+` 
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.parent_id = $1 AND products.deleted_at IS NULL 
+			  ORDER BY products.id`, id)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if categoryName.Valid {
-		p.CategoryName = categoryName.String
+	return p, nil
+}
+
+// GetByBarcode - ambil produk berdasarkan barcode; code harus sudah dinormalisasi
+func (repo *ProductRepository) GetByBarcode(code string) (*models.Product, error) {
+	query := "SELECT " + productColumns + ` 
+			  FROM product_barcodes 
+			  JOIN products ON products.id = product_barcodes.product_id 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE product_barcodes.code = $1 AND products.deleted_at IS NULL`
+
+	p, err := scanProduct(repo.db.QueryRow(query, code))
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
 	}
+	return p, err
+}
 
-	return &p, nil
+// GetIDsByBarcodes - petakan barcode ke product ID; barcode yang tidak terdaftar tidak ada di map
+func (repo *ProductRepository) GetIDsByBarcodes(codes []string) (map[string]int, error) {
+	rows, err := repo.db.Query("SELECT code, product_id FROM product_barcodes WHERE code = ANY($1)", pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var code string
+		var id int
+		if err := rows.Scan(&code, &id); err != nil {
+			return nil, err
+		}
+		ids[code] = id
+	}
+
+	return ids, rows.Err()
 }
 
-func (repo *ProductRepository) Update(product *models.Product) error {
-	query := "UPDATE products SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5"
-	result, err := repo.db.Exec(query, product.Name, product.Price, product.Stock, product.CategoryID, product.ID)
+// Update - ubah data produk; selisih stok dicatat sebagai mutasi "adjustment" di ledger.
+// Daftar barcode diganti seluruhnya dengan product.Barcodes. Jika updateStock false, stok tidak
+// disentuh (PUT, PATCH tanpa field stock) supaya tidak menimpa penjualan yang terjadi sejak produk dibaca.
+func (repo *ProductRepository) Update(product *models.Product, updateStock bool, userID int) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	currentStock, err := updateProduct(tx, product)
 	if err != nil {
 		return err
 	}
+	if !updateStock {
+		product.Stock = currentStock
+		return tx.Commit()
+	}
 
-	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+	if delta := product.Stock - currentStock; delta != 0 {
+		err = applyStockMovement(tx, &models.StockMovement{
+			ProductID: product.ID,
+			Type:      models.StockMovementAdjustment,
+			Quantity:  delta,
+			Note:      "Edit produk",
+			UserID:    userID,
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
+// Delete - hapus permanen; hanya untuk produk yang belum pernah dipakai (lihat ErrProductInUse).
+// Produk induk yang masih punya varian tidak bisa dihapus. Riwayat stock_movements sengaja dibiarkan.
 func (repo *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hasVariants, inUse bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE parent_id = $1),
+			EXISTS (SELECT 1 FROM transaction_details WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM refund_details WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM purchase_order_items WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM goods_receipt_items WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM opname_counts WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM opname_results WHERE product_id = $1)
+			OR EXISTS (SELECT 1 FROM promotions WHERE product_id = $1)
+		FROM products WHERE id = $1 FOR UPDATE`, id).Scan(&hasVariants, &inUse)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if hasVariants {
+		return ErrProductHasVariants
+	}
+	if inUse {
+		return ErrProductInUse
+	}
+
+	if _, err := tx.Exec("DELETE FROM products WHERE id = $1", id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrProductInUse
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Archive - arsipkan produk; varian aktif dari produk induk ikut diarsipkan dengan waktu yang sama
+// supaya bisa dipulihkan bersama
+func (repo *ProductRepository) Archive(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	err = tx.QueryRow("SELECT deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE", id).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrProductArchived
+	}
+
+	_, err = tx.Exec(`UPDATE products SET deleted_at = CURRENT_TIMESTAMP
+		WHERE (id = $1 OR parent_id = $1) AND deleted_at IS NULL`, id)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+
+	return tx.Commit()
+}
+
+// Restore - pulihkan produk yang diarsipkan beserta varian yang ikut diarsipkan bersamanya.
+// Varian hanya bisa dipulihkan jika produk induknya aktif.
+func (repo *ProductRepository) Restore(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt sql.NullTime
+	var parentArchived bool
+	err = tx.QueryRow(`SELECT p.deleted_at, COALESCE(pp.deleted_at IS NOT NULL, FALSE)
+		FROM products p
+		LEFT JOIN products pp ON pp.id = p.parent_id
+		WHERE p.id = $1
+		FOR UPDATE OF p`, id).Scan(&deletedAt, &parentArchived)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !deletedAt.Valid {
+		return ErrProductNotArchived
+	}
+	if parentArchived {
+		return errors.New("produk induk masih diarsipkan, pulihkan produk induk dulu")
+	}
+
+	_, err = tx.Exec(`UPDATE products SET deleted_at = NULL
+		WHERE id = $1 OR (parent_id = $1 AND deleted_at = $2)`, id, deletedAt.Time)
 	if err != nil {
 		return err
 	}
 
-	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+	return tx.Commit()
+}
+
+// insertProduct - simpan produk baru di dalam tx; stok awal dicatat sebagai mutasi "initial"
+func insertProduct(tx *sql.Tx, product *models.Product, userID int) error {
+	query := `INSERT INTO products (name, parent_id, variant_name, sku, price, cost_price, stock, min_stock, category_id, tax_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, $9) RETURNING id`
+	err := tx.QueryRow(query, product.Name, nullableID(product.ParentID), variantNameValue(product), nullableString(product.SKU),
+		product.Price, product.CostPrice, product.MinStock, nullableID(product.CategoryID), product.TaxRate).Scan(&product.ID)
+	if err != nil {
+		return productConstraintError(err)
+	}
+
+	if err := setProductBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return err
+	}
+
+	if product.Stock != 0 {
+		return applyStockMovement(tx, &models.StockMovement{
+			ProductID: product.ID,
+			Type:      models.StockMovementInitial,
+			Quantity:  product.Stock,
+			UserID:    userID,
+		})
+	}
+	return nil
+}
+
+// updateProduct - kunci lalu ubah data produk (kecuali stok) di dalam tx, termasuk nama & kategori variannya.
+// Jika product.Version diisi, harus sama dengan versi di database (optimistic locking); versi baru
+// ditulis balik ke product.Version. Mengembalikan stok saat ini (sudah terkunci) supaya pemanggil
+// bisa mencatat selisihnya.
+func updateProduct(tx *sql.Tx, product *models.Product) (int, error) {
+	var currentStock, version int
+	var archived bool
+	err := tx.QueryRow("SELECT stock, version, deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE", product.ID).
+		Scan(&currentStock, &version, &archived)
+	if err == sql.ErrNoRows {
+		return 0, ErrProductNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if archived {
+		return 0, ErrProductArchived
+	}
+	if product.Version > 0 && product.Version != version {
+		return 0, ErrVersionConflict
+	}
+
+	query := `UPDATE products SET name = $1, variant_name = $2, sku = $3, price = $4, cost_price = $5, min_stock = $6,
+		category_id = $7, tax_rate = $8, version = version + 1
+		WHERE id = $9 RETURNING version`
+	err = tx.QueryRow(query, product.Name, variantNameValue(product), nullableString(product.SKU), product.Price, product.CostPrice,
+		product.MinStock, nullableID(product.CategoryID), product.TaxRate, product.ID).Scan(&product.Version)
+	if err != nil {
+		return 0, productConstraintError(err)
+	}
+
+	// Nama dan kategori varian mengikuti produk induk
+	_, err = tx.Exec("UPDATE products SET name = $1 || ' - ' || variant_name, category_id = $2, version = version + 1 WHERE parent_id = $3",
+		product.Name, nullableID(product.CategoryID), product.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := setProductBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return 0, err
 	}
+	return currentStock, nil
+}
+
+// GetBySKUs - petakan SKU ke produknya; SKU yang tidak terdaftar tidak ada di map
+func (repo *ProductRepository) GetBySKUs(skus []string) (map[string]*models.Product, error) {
+	products, err := repo.queryProducts("SELECT "+productColumns+` 
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.sku = ANY($1)`, pq.Array(skus))
+	if err != nil {
+		return nil, err
+	}
+
+	bySKU := make(map[string]*models.Product, len(products))
+	for i := range products {
+		bySKU[products[i].SKU] = &products[i]
+	}
+	return bySKU, nil
+}
+
+// GetByIDs - petakan ID ke produknya (termasuk yang diarsipkan); ID yang tidak ada tidak ada di map
+func (repo *ProductRepository) GetByIDs(ids []int64) (map[int]*models.Product, error) {
+	products, err := repo.queryProducts("SELECT "+productColumns+` 
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*models.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+	return byID, nil
+}
+
+// GetParentIDs - dari ids, kembalikan yang merupakan produk induk (punya varian)
+func (repo *ProductRepository) GetParentIDs(ids []int64) (map[int]bool, error) {
+	rows, err := repo.db.Query("SELECT DISTINCT parent_id FROM products WHERE parent_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	parents := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		parents[id] = true
+	}
+	return parents, rows.Err()
+}
+
+// ForEach - panggil fn untuk setiap produk aktif (urut id) tanpa memuat seluruh katalog ke memori
+func (repo *ProductRepository) ForEach(fn func(*models.Product) error) error {
+	rows, err := repo.db.Query("SELECT " + productColumns + ` 
+			  FROM products 
+			  LEFT JOIN categories ON products.category_id = categories.id 
+			  WHERE products.deleted_at IS NULL 
+			  ORDER BY products.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Import - simpan hasil import dalam satu DB transaction: buat kategori baru, lalu produk baru dan
+// perubahan produk lama. Produk lama diproses urut ID (sama seperti checkout) supaya tidak deadlock.
+// Perubahan stok produk lama dicatat sebagai mutasi "adjustment".
+func (repo *ProductRepository) Import(changes []models.ProductImportChange, newCategories []string, userID int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	categoryIDs := make(map[string]int, len(newCategories))
+	for _, name := range newCategories {
+		var id int
+		err := tx.QueryRow("INSERT INTO categories (name, description) VALUES ($1, '') RETURNING id", name).Scan(&id)
+		if err != nil {
+			return err
+		}
+		categoryIDs[name] = id
+	}
+
+	changes = slices.Clone(changes)
+	slices.SortStableFunc(changes, func(a, b models.ProductImportChange) int {
+		return a.Product.ID - b.Product.ID
+	})
+
+	for _, change := range changes {
+		product := change.Product
+		if change.NewCategory != "" {
+			product.CategoryID = categoryIDs[change.NewCategory]
+		}
+
+		if product.ID == 0 {
+			if err := insertProduct(tx, product, userID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		currentStock, err := updateProduct(tx, product)
+		if err != nil {
+			return err
+		}
+		if !change.SetStock {
+			continue
+		}
+		if delta := product.Stock - currentStock; delta != 0 {
+			err = applyStockMovement(tx, &models.StockMovement{
+				ProductID: product.ID,
+				Type:      models.StockMovementAdjustment,
+				Quantity:  delta,
+				Note:      "Import produk",
+				UserID:    userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockVariantParent - kunci produk induk sebelum menambah varian. Induk tidak boleh berupa varian
+// dan stoknya harus 0 (stok lama dipindahkan ke varian lebih dulu).
+func lockVariantParent(tx *sql.Tx, parentID int) error {
+	var grandParentID sql.NullInt64
+	var stock int
+	var archived bool
+	err := tx.QueryRow("SELECT parent_id, stock, deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE", parentID).
+		Scan(&grandParentID, &stock, &archived)
+	if err == sql.ErrNoRows {
+		return errors.New("produk induk tidak ditemukan")
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return errors.New("produk induk sudah diarsipkan")
+	}
+	if grandParentID.Valid {
+		return errors.New("varian tidak bisa punya varian")
+	}
+	if stock != 0 {
+		return fmt.Errorf("stok produk induk harus 0 sebelum menambah varian (stok sekarang: %d)", stock)
+	}
+	return nil
+}
+
+// variantNameValue - variant_name NULL untuk produk biasa / induk
+func variantNameValue(product *models.Product) interface{} {
+	if product.ParentID == 0 {
+		return nil
+	}
+	return product.VariantName
+}
+
+// setProductBarcodes - ganti semua barcode produk; codes harus sudah divalidasi dan dinormalisasi
+func setProductBarcodes(tx *sql.Tx, productID int, codes []string) error {
+	if _, err := tx.Exec("DELETE FROM product_barcodes WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		_, err := tx.Exec("INSERT INTO product_barcodes (product_id, code) VALUES ($1, $2)", productID, code)
+		if err != nil {
+			return productConstraintError(err)
+		}
+	}
+	return nil
+}
+
+// productConstraintError - terjemahkan pelanggaran unique sku/barcode ke error yang bisa dibaca kasir
+func productConstraintError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		switch pqErr.Constraint {
+		case "idx_products_sku":
+			return ErrDuplicateSKU
+		case "product_barcodes_code_key":
+			return ErrDuplicateBarcode
+		case "idx_products_parent_variant":
+			return ErrDuplicateVariant
+		}
+	}
 	return err
 }
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"time"
+)
+
+type PromotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionColumns = `id, name, type, value, product_id, category_id, buy_qty, get_qty,
+	bundle_qty, bundle_price, start_at, end_at, is_active`
+
+func scanPromotion(scanner interface{ Scan(...interface{}) error }) (*models.Promotion, error) {
+	var p models.Promotion
+	var productID, categoryID sql.NullInt64
+	err := scanner.Scan(&p.ID, &p.Name, &p.Type, &p.Value, &productID, &categoryID, &p.BuyQty, &p.GetQty,
+		&p.BundleQty, &p.BundlePrice, &p.StartAt, &p.EndAt, &p.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	if productID.Valid {
+		p.ProductID = int(productID.Int64)
+	}
+	if categoryID.Valid {
+		p.CategoryID = int(categoryID.Int64)
+	}
+	return &p, nil
+}
+
+func (repo *PromotionRepository) queryPromotions(query string, args ...interface{}) ([]models.Promotion, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := make([]models.Promotion, 0)
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, *p)
+	}
+
+	return promotions, rows.Err()
+}
+
+func (repo *PromotionRepository) GetAll() ([]models.Promotion, error) {
+	return repo.queryPromotions("SELECT " + promotionColumns + " FROM promotions ORDER BY start_at DESC, id DESC")
+}
+
+// GetActive - promo aktif yang periodenya mencakup waktu at
+func (repo *PromotionRepository) GetActive(at time.Time) ([]models.Promotion, error) {
+	return repo.queryPromotions(
+		"SELECT "+promotionColumns+" FROM promotions WHERE is_active AND start_at <= $1 AND end_at >= $1 ORDER BY id",
+		at,
+	)
+}
+
+func (repo *PromotionRepository) GetByID(id int) (*models.Promotion, error) {
+	p, err := scanPromotion(repo.db.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("promo tidak ditemukan")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (repo *PromotionRepository) Create(p *models.Promotion) error {
+	query := `INSERT INTO promotions (name, type, value, product_id, category_id, buy_qty, get_qty,
+		bundle_qty, bundle_price, start_at, end_at, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return repo.db.QueryRow(query, p.Name, p.Type, p.Value, nullableID(p.ProductID), nullableID(p.CategoryID),
+		p.BuyQty, p.GetQty, p.BundleQty, p.BundlePrice, p.StartAt, p.EndAt, p.IsActive).Scan(&p.ID)
+}
+
+func (repo *PromotionRepository) Update(p *models.Promotion) error {
+	query := `UPDATE promotions SET name = $1, type = $2, value = $3, product_id = $4, category_id = $5,
+		buy_qty = $6, get_qty = $7, bundle_qty = $8, bundle_price = $9, start_at = $10, end_at = $11,
+		is_active = $12, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $13`
+	result, err := repo.db.Exec(query, p.Name, p.Type, p.Value, nullableID(p.ProductID), nullableID(p.CategoryID),
+		p.BuyQty, p.GetQty, p.BundleQty, p.BundlePrice, p.StartAt, p.EndAt, p.IsActive, p.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errors.New("promo tidak ditemukan")
+	}
+
+	return nil
+}
+
+func (repo *PromotionRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errors.New("promo tidak ditemukan")
+	}
+
+	return nil
+}
+
+// nullableID - simpan 0 sebagai NULL untuk kolom foreign key opsional
+func nullableID(id int) interface{} {
+	if id <= 0 {
+		return nil
+	}
+	return id
+}
+
+// nullableString - simpan string kosong sebagai NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,450 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"sort"
+)
+
+var (
+	ErrPurchaseOrderNotFound = errors.New("purchase order tidak ditemukan")
+	// ErrPurchaseOrderStatus - aksi tidak boleh dilakukan pada status PO saat ini
+	ErrPurchaseOrderStatus = errors.New("status purchase order tidak mengizinkan aksi ini")
+)
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{db: db}
+}
+
+const purchaseOrderColumns = `po.id, po.supplier_id, s.name, po.status, po.note, po.total_cost, po.created_by, po.created_at,
+	po.ordered_at, po.received_at, po.cancelled_at`
+
+func scanPurchaseOrder(scanner interface{ Scan(...interface{}) error }) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	var createdBy sql.NullInt64
+	var orderedAt, receivedAt, cancelledAt sql.NullTime
+	err := scanner.Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.Note, &po.TotalCost, &createdBy, &po.CreatedAt,
+		&orderedAt, &receivedAt, &cancelledAt)
+	if err != nil {
+		return nil, err
+	}
+	po.CreatedBy = int(createdBy.Int64)
+	if orderedAt.Valid {
+		po.OrderedAt = &orderedAt.Time
+	}
+	if receivedAt.Valid {
+		po.ReceivedAt = &receivedAt.Time
+	}
+	if cancelledAt.Valid {
+		po.CancelledAt = &cancelledAt.Time
+	}
+	return &po, nil
+}
+
+// Create - simpan PO baru berstatus draft beserta item-itemnya
+func (repo *PurchaseOrderRepository) Create(req models.PurchaseOrderRequest, userID int) (*models.PurchaseOrder, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(
+		"INSERT INTO purchase_orders (supplier_id, note, created_by) VALUES ($1, $2, $3) RETURNING id",
+		req.SupplierID, req.Note, nullableID(userID),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertPurchaseOrderItems(tx, id, req.Items); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetByID(id)
+}
+
+// UpdateDraft - ubah supplier, catatan dan item PO; hanya untuk PO yang masih draft
+func (repo *PurchaseOrderRepository) UpdateDraft(id int, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET supplier_id = $1, note = $2 WHERE id = $3", req.SupplierID, req.Note, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", id); err != nil {
+		return nil, err
+	}
+	if err := insertPurchaseOrderItems(tx, id, req.Items); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetByID(id)
+}
+
+// lockPurchaseOrder - kunci baris PO dan pastikan statusnya salah satu dari allowed
+func lockPurchaseOrder(tx *sql.Tx, id int, allowed ...string) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range allowed {
+		if status == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (status: %s)", ErrPurchaseOrderStatus, status)
+}
+
+// insertPurchaseOrderItems - simpan item dengan snapshot nama produk lalu hitung ulang total_cost
+func insertPurchaseOrderItems(tx *sql.Tx, purchaseOrderID int, items []models.PurchaseOrderItemInput) error {
+	for _, item := range items {
+		var itemID int
+		err := tx.QueryRow(
+			`INSERT INTO purchase_order_items (purchase_order_id, product_id, product_name, quantity, unit_cost)
+			SELECT $1, id, name, $3, $4 FROM products WHERE id = $2 AND deleted_at IS NULL
+			RETURNING id`,
+			purchaseOrderID, item.ProductID, item.Quantity, item.UnitCost,
+		).Scan(&itemID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("product id %d tidak ditemukan atau sudah diarsipkan", item.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(
+		`UPDATE purchase_orders SET total_cost = (
+			SELECT COALESCE(SUM(quantity * unit_cost), 0) FROM purchase_order_items WHERE purchase_order_id = $1
+		) WHERE id = $1`,
+		purchaseOrderID,
+	)
+	return err
+}
+
+// GetAll - daftar PO terbaru lebih dulu, tanpa item
+func (repo *PurchaseOrderRepository) GetAll(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	query := "SELECT " + purchaseOrderColumns + " FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id WHERE 1=1"
+	args := []interface{}{}
+	if filter.SupplierID > 0 {
+		args = append(args, filter.SupplierID)
+		query += fmt.Sprintf(" AND po.supplier_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND po.status = $%d", len(args))
+	}
+	query += " ORDER BY po.created_at DESC, po.id DESC"
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.PurchaseOrder, 0)
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *po)
+	}
+
+	return orders, rows.Err()
+}
+
+// GetByID - detail PO beserta item dan riwayat penerimaan barang
+func (repo *PurchaseOrderRepository) GetByID(id int) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(repo.db.QueryRow(
+		"SELECT "+purchaseOrderColumns+" FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id WHERE po.id = $1", id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	po.Items, err = repo.getItems(id)
+	if err != nil {
+		return nil, err
+	}
+	po.Receipts, err = repo.getReceipts(id)
+	if err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func (repo *PurchaseOrderRepository) getItems(purchaseOrderID int) ([]models.PurchaseOrderItem, error) {
+	rows, err := repo.db.Query(
+		`SELECT id, purchase_order_id, product_id, product_name, quantity, received_qty, unit_cost
+		FROM purchase_order_items WHERE purchase_order_id = $1 ORDER BY id`,
+		purchaseOrderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.PurchaseOrderItem, 0)
+	for rows.Next() {
+		var item models.PurchaseOrderItem
+		err := rows.Scan(&item.ID, &item.PurchaseOrderID, &item.ProductID, &item.ProductName, &item.Quantity,
+			&item.ReceivedQty, &item.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		item.Subtotal = item.Quantity * item.UnitCost
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (repo *PurchaseOrderRepository) getReceipts(purchaseOrderID int) ([]models.GoodsReceipt, error) {
+	rows, err := repo.db.Query(
+		`SELECT gr.id, gr.purchase_order_id, gr.note, gr.user_id, gr.received_at,
+			gri.id, gri.product_id, poi.product_name, gri.quantity, gri.unit_cost
+		FROM goods_receipts gr
+		JOIN goods_receipt_items gri ON gri.receipt_id = gr.id
+		JOIN purchase_order_items poi ON poi.id = gri.purchase_order_item_id
+		WHERE gr.purchase_order_id = $1
+		ORDER BY gr.received_at, gr.id, gri.id`,
+		purchaseOrderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := make([]models.GoodsReceipt, 0)
+	for rows.Next() {
+		var receipt models.GoodsReceipt
+		var userID sql.NullInt64
+		var item models.GoodsReceiptItem
+		err := rows.Scan(&receipt.ID, &receipt.PurchaseOrderID, &receipt.Note, &userID, &receipt.ReceivedAt,
+			&item.ID, &item.ProductID, &item.ProductName, &item.Quantity, &item.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		item.ReceiptID = receipt.ID
+
+		if n := len(receipts); n > 0 && receipts[n-1].ID == receipt.ID {
+			receipts[n-1].Items = append(receipts[n-1].Items, item)
+			continue
+		}
+		receipt.UserID = int(userID.Int64)
+		receipt.Items = []models.GoodsReceiptItem{item}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, rows.Err()
+}
+
+// MarkOrdered - draft -> ordered
+func (repo *PurchaseOrderRepository) MarkOrdered(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE purchase_orders SET status = $1, ordered_at = CURRENT_TIMESTAMP WHERE id = $2",
+		models.PurchaseOrderStatusOrdered, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel - batalkan PO yang belum diterima penuh. Barang yang sudah diterima tetap masuk stok;
+// sisa pesanan tidak bisa diterima lagi.
+func (repo *PurchaseOrderRepository) Cancel(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockPurchaseOrder(tx, id, models.PurchaseOrderStatusDraft, models.PurchaseOrderStatusOrdered,
+		models.PurchaseOrderStatusPartiallyReceived)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE purchase_orders SET status = $1, cancelled_at = CURRENT_TIMESTAMP WHERE id = $2",
+		models.PurchaseOrderStatusCancelled, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Receive - catat penerimaan barang: tambah received_qty, perbarui HPP produk (rata-rata tertimbang),
+// tambah stok lewat ledger (mutasi purchase), lalu set status partially_received / received. PO dikunci lebih dulu, produk diubah berurutan by ID.
+func (repo *PurchaseOrderRepository) Receive(id int, req models.GoodsReceiptRequest, userID int) (*models.GoodsReceipt, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = lockPurchaseOrder(tx, id, models.PurchaseOrderStatusOrdered, models.PurchaseOrderStatusPartiallyReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		"SELECT id, product_id, product_name, quantity, received_qty, unit_cost FROM purchase_order_items WHERE purchase_order_id = $1",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	poItems := make(map[int]*models.PurchaseOrderItem)
+	for rows.Next() {
+		item := &models.PurchaseOrderItem{PurchaseOrderID: id}
+		err := rows.Scan(&item.ID, &item.ProductID, &item.ProductName, &item.Quantity, &item.ReceivedQty, &item.UnitCost)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		poItems[item.ProductID] = item
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	inputs := make([]models.GoodsReceiptItemInput, len(req.Items))
+	copy(inputs, req.Items)
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].ProductID < inputs[j].ProductID })
+
+	for _, input := range inputs {
+		item, ok := poItems[input.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("product id %d tidak ada di purchase order ini", input.ProductID)
+		}
+		if remaining := item.Quantity - item.ReceivedQty; input.Quantity > remaining {
+			return nil, fmt.Errorf("quantity %s melebihi sisa pesanan (sisa: %d, diterima: %d)", item.ProductName, remaining, input.Quantity)
+		}
+	}
+
+	receipt := &models.GoodsReceipt{
+		PurchaseOrderID: id,
+		Note:            req.Note,
+		UserID:          userID,
+		Items:           make([]models.GoodsReceiptItem, 0, len(inputs)),
+	}
+	err = tx.QueryRow(
+		"INSERT INTO goods_receipts (purchase_order_id, note, user_id) VALUES ($1, $2, $3) RETURNING id, received_at",
+		id, req.Note, nullableID(userID),
+	).Scan(&receipt.ID, &receipt.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, input := range inputs {
+		item := poItems[input.ProductID]
+		if _, err := tx.Exec("UPDATE purchase_order_items SET received_qty = received_qty + $1 WHERE id = $2", input.Quantity, item.ID); err != nil {
+			return nil, err
+		}
+		item.ReceivedQty += input.Quantity
+
+		receiptItem := models.GoodsReceiptItem{
+			ReceiptID:   receipt.ID,
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    input.Quantity,
+			UnitCost:    item.UnitCost,
+		}
+		err := tx.QueryRow(
+			`INSERT INTO goods_receipt_items (receipt_id, purchase_order_item_id, product_id, quantity, unit_cost)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			receipt.ID, item.ID, item.ProductID, input.Quantity, item.UnitCost,
+		).Scan(&receiptItem.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// HPP rata-rata tertimbang: (stok lama * HPP lama + qty diterima * harga beli) / stok baru.
+		// Stok lama yang negatif (data lama sebelum constraint) dianggap 0 supaya HPP tidak melonjak
+		// atau dibagi nol.
+		_, err = tx.Exec(
+			`UPDATE products SET cost_price = ROUND((GREATEST(stock, 0)::numeric * cost_price + $1::numeric * $2) / (GREATEST(stock, 0) + $1))
+			WHERE id = $3`,
+			input.Quantity, item.UnitCost, item.ProductID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = applyStockMovement(tx, &models.StockMovement{
+			ProductID:     item.ProductID,
+			Type:          models.StockMovementPurchase,
+			Quantity:      input.Quantity,
+			ReferenceType: models.StockReferenceGoodsReceipt,
+			ReferenceID:   receipt.ID,
+			Note:          fmt.Sprintf("Penerimaan PO #%d", id),
+			UserID:        userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		receipt.Items = append(receipt.Items, receiptItem)
+	}
+
+	status := models.PurchaseOrderStatusReceived
+	for _, item := range poItems {
+		if item.ReceivedQty < item.Quantity {
+			status = models.PurchaseOrderStatusPartiallyReceived
+			break
+		}
+	}
+	_, err = tx.Exec(
+		`UPDATE purchase_orders SET status = $1,
+			received_at = CASE WHEN $1 = 'received' THEN CURRENT_TIMESTAMP ELSE received_at END
+		WHERE id = $2`,
+		status, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
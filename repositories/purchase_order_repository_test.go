@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"kasir-api/models"
+	"testing"
+)
+
+// TestReceiveWeightedCostIgnoresNegativeStock - stok lama negatif tidak boleh ikut ditimbang:
+// HPP baru cukup harga beli barang yang diterima.
+func TestReceiveWeightedCostIgnoresNegativeStock(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPurchaseOrderRepository(db)
+
+	// Constraint stok non-negatif dibuat NOT VALID; buang di schema test untuk meniru data lama
+	if _, err := db.Exec("ALTER TABLE products DROP CONSTRAINT IF EXISTS products_stock_non_negative"); err != nil {
+		t.Fatal(err)
+	}
+
+	var supplierID, productID int
+	if err := db.QueryRow("INSERT INTO suppliers (name) VALUES ('Supplier test') RETURNING id").Scan(&supplierID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("INSERT INTO products (name, price, stock, cost_price) VALUES ('Produk HPP', 5000, -3, 1000) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	po, err := repo.Create(models.PurchaseOrderRequest{
+		SupplierID: supplierID,
+		Items:      []models.PurchaseOrderItemInput{{ProductID: productID, Quantity: 5, UnitCost: 2000}},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkOrdered(po.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Receive(po.ID, models.GoodsReceiptRequest{
+		Items: []models.GoodsReceiptItemInput{{ProductID: productID, Quantity: 5}},
+	}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var stock, costPrice int
+	if err := db.QueryRow("SELECT stock, cost_price FROM products WHERE id = $1", productID).Scan(&stock, &costPrice); err != nil {
+		t.Fatal(err)
+	}
+	if stock != 2 {
+		t.Errorf("stok = %d, want 2", stock)
+	}
+	if costPrice != 2000 {
+		t.Errorf("cost_price = %d, want 2000", costPrice)
+	}
+}
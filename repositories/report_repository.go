@@ -3,6 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"kasir-api/models"
+	"math"
+	"sort"
 	"time"
 )
 
@@ -19,28 +21,75 @@ func (repo *ReportRepository) GetDailySalesReport() (*models.DailySalesReport, e
 	return repo.GetSalesReportByDateRange(today, today)
 }
 
+// netTaxLinesCTE - baris penjualan dari transaksi yang tidak di-void pada rentang $1..$2, dengan DPP dan pajak
+// dikurangi porsi qty yang sudah di-refund (proporsional terhadap quantity baris). Dipakai laporan penjualan,
+// laporan per kasir dan laporan pajak supaya total pajaknya selalu sama.
+const netTaxLinesCTE = `
+	net_tax_lines AS (
+		SELECT DATE(t.created_at) AS tanggal, COALESCE(t.cashier_id, 0) AS cashier_id, td.tax_rate,
+			ROUND((td.subtotal - CASE WHEN t.tax_inclusive THEN td.tax_amount ELSE 0 END)::numeric
+				* (td.quantity - COALESCE(rd.qty, 0)) / td.quantity)::bigint AS dpp,
+			ROUND(td.tax_amount::numeric * (td.quantity - COALESCE(rd.qty, 0)) / td.quantity)::bigint AS pajak
+		FROM transaction_details td
+		JOIN transactions t ON td.transaction_id = t.id
+		LEFT JOIN (
+			SELECT transaction_detail_id, SUM(quantity) AS qty
+			FROM refund_details
+			GROUP BY transaction_detail_id
+		) rd ON rd.transaction_detail_id = td.id
+		WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
+		  AND t.status <> 'voided'
+	)`
+
 func (repo *ReportRepository) GetSalesReportByDateRange(startDate, endDate string) (*models.DailySalesReport, error) {
 	report := &models.DailySalesReport{}
 
-	// Get total revenue and total transactions
+	// Transaksi yang di-void tidak dihitung sama sekali; pajak dikurangi pajak baris yang sudah di-refund
 	summaryQuery := `
-		SELECT COALESCE(SUM(total_amount), 0), COUNT(*)
+		WITH ` + netTaxLinesCTE + `
+		SELECT COALESCE(SUM(subtotal_amount), 0), COALESCE(SUM(discount_amount), 0),
+			(SELECT COALESCE(SUM(pajak), 0)::bigint FROM net_tax_lines), COALESCE(SUM(service_charge_amount), 0),
+			COALESCE(SUM(total_amount), 0), COUNT(*)
 		FROM transactions
 		WHERE DATE(created_at) >= $1 AND DATE(created_at) <= $2
+		  AND status <> 'voided'
+	`
+	var grandTotal int
+	err := repo.db.QueryRow(summaryQuery, startDate, endDate).Scan(&report.GrossRevenue, &report.TotalDiskon,
+		&report.TotalPajak, &report.TotalServiceCharge, &grandTotal, &report.TotalTransaksi)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refund dikurangkan dari revenue pada tanggal refund dilakukan; refund milik transaksi yang
+	// akhirnya di-void tidak dihitung karena transaksinya sudah tidak masuk grand total
+	refundQuery := `
+		SELECT COALESCE(SUM(r.total_amount), 0)
+		FROM refunds r
+		JOIN transactions t ON r.transaction_id = t.id
+		WHERE DATE(r.created_at) >= $1 AND DATE(r.created_at) <= $2
+		  AND t.status <> 'voided'
 	`
-	err := repo.db.QueryRow(summaryQuery, startDate, endDate).Scan(&report.TotalRevenue, &report.TotalTransaksi)
+	err = repo.db.QueryRow(refundQuery, startDate, endDate).Scan(&report.TotalRefund)
 	if err != nil {
 		return nil, err
 	}
+	report.TotalRevenue = grandTotal - report.TotalRefund
 
 	// Get top selling product
 	topProductQuery := `
-		SELECT p.name, COALESCE(SUM(td.quantity), 0) as total_qty
+		SELECT (ARRAY_AGG(td.product_name ORDER BY t.created_at DESC))[1],
+			COALESCE(SUM(td.quantity - COALESCE(rd.qty, 0)), 0) as total_qty
 		FROM transaction_details td
 		JOIN transactions t ON td.transaction_id = t.id
-		JOIN products p ON td.product_id = p.id
+		LEFT JOIN (
+			SELECT transaction_detail_id, SUM(quantity) AS qty
+			FROM refund_details
+			GROUP BY transaction_detail_id
+		) rd ON rd.transaction_detail_id = td.id
 		WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
-		GROUP BY p.id, p.name
+		GROUP BY td.product_id
+		HAVING SUM(td.quantity - COALESCE(rd.qty, 0)) > 0
 		ORDER BY total_qty DESC
 		LIMIT 1
 	`
@@ -54,5 +103,408 @@ func (repo *ReportRepository) GetSalesReportByDateRange(startDate, endDate strin
 		report.ProdukTerlaris = &topProduct
 	}
 
+	report.PerMetodePembayaran, err = repo.getRevenueByPaymentMethod(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.fillProfit(report, startDate, endDate); err != nil {
+		return nil, err
+	}
+
 	return report, nil
 }
+
+// fillProfit - HPP, laba kotor dan margin keseluruhan, per produk (varian), per produk induk dan per kategori.
+// Nilai jual per item = subtotal tanpa pajak (untuk harga inklusif), proporsional terhadap qty yang tidak di-refund.
+func (repo *ReportRepository) fillProfit(report *models.DailySalesReport, startDate, endDate string) error {
+	query := `
+		WITH lines AS (
+			SELECT COALESCE(td.product_id, 0) AS product_id, td.product_name, t.created_at,
+				COALESCE(td.parent_product_id, 0) AS parent_id, COALESCE(pp.name, td.product_name) AS parent_name,
+				COALESCE(td.category_id, 0) AS category_id, COALESCE(td.category_name, '') AS category_name,
+				td.quantity - COALESCE(rd.qty, 0) AS qty,
+				ROUND((td.subtotal - CASE WHEN t.tax_inclusive THEN td.tax_amount ELSE 0 END)::numeric
+					* (td.quantity - COALESCE(rd.qty, 0)) / td.quantity) AS sales,
+				td.unit_cost * (td.quantity - COALESCE(rd.qty, 0)) AS cogs
+			FROM transaction_details td
+			JOIN transactions t ON td.transaction_id = t.id
+			LEFT JOIN products pp ON pp.id = td.parent_product_id
+			LEFT JOIN (
+				SELECT transaction_detail_id, SUM(quantity) AS qty
+				FROM refund_details
+				GROUP BY transaction_detail_id
+			) rd ON rd.transaction_detail_id = td.id
+			WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
+			  AND t.status <> 'voided'
+		)
+		SELECT product_id, (ARRAY_AGG(product_name ORDER BY created_at DESC))[1],
+			parent_id, (ARRAY_AGG(parent_name ORDER BY created_at DESC))[1], category_id, category_name,
+			SUM(qty), SUM(sales)::int, SUM(cogs)
+		FROM lines
+		GROUP BY product_id, parent_id, category_id, category_name
+		HAVING SUM(qty) > 0
+	`
+	rows, err := repo.db.Query(query, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	products := make(map[int]*models.ProductProfitSummary)
+	parents := make(map[int]*models.ProductProfitSummary)
+	categories := make(map[int]*models.CategoryProfitSummary)
+	report.PerProduk = make([]models.ProductProfitSummary, 0)
+	report.PerProdukInduk = make([]models.ProductProfitSummary, 0)
+	report.PerKategori = make([]models.CategoryProfitSummary, 0)
+	productOrder := make([]int, 0)
+	parentOrder := make([]int, 0)
+	categoryOrder := make([]int, 0)
+
+	for rows.Next() {
+		var productID, parentID, categoryID, qty, sales, cogs int
+		var productName, parentName, categoryName string
+		err := rows.Scan(&productID, &productName, &parentID, &parentName, &categoryID, &categoryName, &qty, &sales, &cogs)
+		if err != nil {
+			return err
+		}
+
+		p, ok := products[productID]
+		if !ok {
+			p = &models.ProductProfitSummary{ProductID: productID, ParentProductID: parentID, Nama: productName}
+			products[productID] = p
+			productOrder = append(productOrder, productID)
+		}
+		p.QtyTerjual += qty
+		p.PenjualanBersih += sales
+		p.TotalHPP += cogs
+
+		// Produk tanpa varian menjadi induknya sendiri
+		rollupID, rollupName := parentID, parentName
+		if parentID == 0 {
+			rollupID, rollupName = productID, productName
+		}
+		pp, ok := parents[rollupID]
+		if !ok {
+			pp = &models.ProductProfitSummary{ProductID: rollupID, Nama: rollupName}
+			parents[rollupID] = pp
+			parentOrder = append(parentOrder, rollupID)
+		}
+		pp.QtyTerjual += qty
+		pp.PenjualanBersih += sales
+		pp.TotalHPP += cogs
+
+		c, ok := categories[categoryID]
+		if !ok {
+			c = &models.CategoryProfitSummary{CategoryID: categoryID, Nama: categoryName}
+			categories[categoryID] = c
+			categoryOrder = append(categoryOrder, categoryID)
+		}
+		c.QtyTerjual += qty
+		c.PenjualanBersih += sales
+		c.TotalHPP += cogs
+
+		report.PenjualanBersih += sales
+		report.TotalHPP += cogs
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range productOrder {
+		p := products[id]
+		p.LabaKotor = p.PenjualanBersih - p.TotalHPP
+		p.MarginPersen = marginPercent(p.LabaKotor, p.PenjualanBersih)
+		report.PerProduk = append(report.PerProduk, *p)
+	}
+	for _, id := range parentOrder {
+		p := parents[id]
+		p.LabaKotor = p.PenjualanBersih - p.TotalHPP
+		p.MarginPersen = marginPercent(p.LabaKotor, p.PenjualanBersih)
+		report.PerProdukInduk = append(report.PerProdukInduk, *p)
+	}
+	for _, id := range categoryOrder {
+		c := categories[id]
+		c.LabaKotor = c.PenjualanBersih - c.TotalHPP
+		c.MarginPersen = marginPercent(c.LabaKotor, c.PenjualanBersih)
+		report.PerKategori = append(report.PerKategori, *c)
+	}
+	sort.SliceStable(report.PerProduk, func(i, j int) bool { return report.PerProduk[i].LabaKotor > report.PerProduk[j].LabaKotor })
+	sort.SliceStable(report.PerProdukInduk, func(i, j int) bool {
+		return report.PerProdukInduk[i].LabaKotor > report.PerProdukInduk[j].LabaKotor
+	})
+	sort.SliceStable(report.PerKategori, func(i, j int) bool { return report.PerKategori[i].LabaKotor > report.PerKategori[j].LabaKotor })
+
+	report.LabaKotor = report.PenjualanBersih - report.TotalHPP
+	report.MarginPersen = marginPercent(report.LabaKotor, report.PenjualanBersih)
+	return nil
+}
+
+// marginPercent - laba kotor terhadap penjualan bersih dalam persen, dibulatkan 2 desimal
+func marginPercent(profit, sales int) float64 {
+	if sales == 0 {
+		return 0
+	}
+	return math.Round(float64(profit)/float64(sales)*10000) / 100
+}
+
+// GetSalesByCashier - rekap penjualan per kasir. Transaksi void tidak dihitung di angka mana pun dan
+// pajaknya neto setelah refund, sama seperti GetSalesReportByDateRange dan GetTaxReport. Refund dihitung
+// ke user yang memproses refund pada tanggal refund.
+func (repo *ReportRepository) GetSalesByCashier(startDate, endDate string) ([]models.CashierSalesSummary, error) {
+	query := `
+		WITH ` + netTaxLinesCTE + `, sales AS (
+			SELECT COALESCE(cashier_id, 0) AS cashier_id, COUNT(*) AS total_transaksi,
+				SUM(subtotal_amount) AS gross, SUM(discount_amount) AS diskon,
+				SUM(service_charge_amount) AS service_charge, SUM(total_amount) AS grand_total
+			FROM transactions
+			WHERE DATE(created_at) >= $1 AND DATE(created_at) <= $2
+			  AND status <> 'voided'
+			GROUP BY COALESCE(cashier_id, 0)
+		), tax AS (
+			SELECT cashier_id, SUM(pajak) AS total
+			FROM net_tax_lines
+			GROUP BY cashier_id
+		), refunded AS (
+			SELECT COALESCE(r.user_id, 0) AS cashier_id, SUM(r.total_amount) AS total
+			FROM refunds r
+			JOIN transactions t ON r.transaction_id = t.id
+			WHERE DATE(r.created_at) >= $1 AND DATE(r.created_at) <= $2
+			  AND t.status <> 'voided'
+			GROUP BY COALESCE(r.user_id, 0)
+		), cash AS (
+			SELECT COALESCE(t.cashier_id, 0) AS cashier_id, SUM(p.amount) AS total
+			FROM payments p
+			JOIN transactions t ON p.transaction_id = t.id
+			WHERE p.method = 'cash' AND DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
+			  AND t.status <> 'voided'
+			GROUP BY COALESCE(t.cashier_id, 0)
+		)
+		SELECT COALESCE(s.cashier_id, r.cashier_id) AS id,
+			COALESCE(NULLIF(u.name, ''), u.username, ''),
+			COALESCE(s.total_transaksi, 0), COALESCE(s.gross, 0), COALESCE(s.diskon, 0),
+			COALESCE(tx.total, 0)::bigint, COALESCE(s.service_charge, 0), COALESCE(s.grand_total, 0),
+			COALESCE(r.total, 0), COALESCE(c.total, 0)
+		FROM sales s
+		FULL JOIN refunded r ON s.cashier_id = r.cashier_id
+		LEFT JOIN tax tx ON tx.cashier_id = s.cashier_id
+		LEFT JOIN cash c ON c.cashier_id = COALESCE(s.cashier_id, r.cashier_id)
+		LEFT JOIN users u ON u.id = COALESCE(s.cashier_id, r.cashier_id)
+		ORDER BY COALESCE(s.grand_total, 0) - COALESCE(r.total, 0) DESC, id
+	`
+	rows, err := repo.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.CashierSalesSummary, 0)
+	for rows.Next() {
+		var s models.CashierSalesSummary
+		var grandTotal int
+		err := rows.Scan(&s.CashierID, &s.CashierName, &s.TotalTransaksi, &s.GrossRevenue, &s.TotalDiskon,
+			&s.TotalPajak, &s.TotalServiceCharge, &grandTotal, &s.TotalRefund, &s.TotalTunai)
+		if err != nil {
+			return nil, err
+		}
+		s.TotalRevenue = grandTotal - s.TotalRefund
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetShiftReport - angka Z-report untuk satu shift: penjualan dan pembayaran dari transaksi
+// yang terjadi di shift tersebut, refund yang diproses di shift tersebut, dan perhitungan kas.
+// Transaksi void dan refund-nya tidak dihitung di angka penjualan (pajak neto setelah refund,
+// sama seperti laporan pajak); laci kas tetap mencatat uang yang benar-benar keluar masuk.
+// Field Shift diisi oleh pemanggil.
+func (repo *ReportRepository) GetShiftReport(shiftID int) (*models.ShiftReport, error) {
+	report := &models.ShiftReport{}
+
+	summaryQuery := `
+		SELECT COALESCE(SUM(subtotal_amount), 0), COALESCE(SUM(discount_amount), 0),
+			(
+				SELECT COALESCE(SUM(ROUND(td.tax_amount::numeric * (td.quantity - COALESCE(rd.qty, 0)) / td.quantity)), 0)::bigint
+				FROM transaction_details td
+				JOIN transactions t ON td.transaction_id = t.id
+				LEFT JOIN (
+					SELECT transaction_detail_id, SUM(quantity) AS qty
+					FROM refund_details
+					GROUP BY transaction_detail_id
+				) rd ON rd.transaction_detail_id = td.id
+				WHERE t.shift_id = $1 AND t.status <> 'voided'
+			),
+			COALESCE(SUM(service_charge_amount), 0), COALESCE(SUM(total_amount), 0), COUNT(*)
+		FROM transactions
+		WHERE shift_id = $1 AND status <> 'voided'
+	`
+	err := repo.db.QueryRow(summaryQuery, shiftID).Scan(&report.GrossRevenue, &report.TotalDiskon,
+		&report.TotalPajak, &report.TotalServiceCharge, &report.TotalPenjualan, &report.TotalTransaksi)
+	if err != nil {
+		return nil, err
+	}
+
+	refundQuery := `
+		SELECT COALESCE(SUM(r.total_amount), 0)
+		FROM refunds r
+		JOIN transactions t ON r.transaction_id = t.id
+		WHERE r.shift_id = $1 AND t.status <> 'voided'
+	`
+	err = repo.db.QueryRow(refundQuery, shiftID).Scan(&report.TotalRefund)
+	if err != nil {
+		return nil, err
+	}
+
+	methodQuery := `
+		SELECT p.method, COALESCE(SUM(p.amount), 0), COUNT(DISTINCT p.transaction_id)
+		FROM payments p
+		JOIN transactions t ON p.transaction_id = t.id
+		WHERE t.shift_id = $1 AND t.status <> 'voided'
+		GROUP BY p.method
+		ORDER BY SUM(p.amount) DESC
+	`
+	rows, err := repo.db.Query(methodQuery, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.PerMetodePembayaran = make([]models.PaymentMethodSummary, 0)
+	for rows.Next() {
+		var s models.PaymentMethodSummary
+		if err := rows.Scan(&s.Metode, &s.Total, &s.JumlahTransaksi); err != nil {
+			return nil, err
+		}
+		report.PerMetodePembayaran = append(report.PerMetodePembayaran, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cash, err := getShiftCashSummary(repo.db, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	report.Kas = *cash
+
+	return report, nil
+}
+
+// getRevenueByPaymentMethod - total pembayaran (setelah kembalian) per metode, transaksi void tidak dihitung.
+// Refund pada rentang tanggal dikurangkan dari tiap metode sesuai porsi metode itu di transaksi asalnya.
+func (repo *ReportRepository) getRevenueByPaymentMethod(startDate, endDate string) ([]models.PaymentMethodSummary, error) {
+	query := `
+		WITH paid AS (
+			SELECT p.method, SUM(p.amount) AS total, COUNT(DISTINCT p.transaction_id) AS jumlah
+			FROM payments p
+			JOIN transactions t ON p.transaction_id = t.id
+			WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
+			  AND t.status <> 'voided'
+			GROUP BY p.method
+		), refunded AS (
+			SELECT p.method, SUM(ROUND(r.total_amount::numeric * p.amount / NULLIF(t.total_amount, 0)))::bigint AS total
+			FROM refunds r
+			JOIN transactions t ON r.transaction_id = t.id
+			JOIN payments p ON p.transaction_id = t.id
+			WHERE DATE(r.created_at) >= $1 AND DATE(r.created_at) <= $2
+			  AND t.status <> 'voided'
+			GROUP BY p.method
+		)
+		SELECT COALESCE(pd.method, rf.method),
+			COALESCE(pd.total, 0) - COALESCE(rf.total, 0) AS net, COALESCE(pd.jumlah, 0)
+		FROM paid pd
+		FULL JOIN refunded rf ON rf.method = pd.method
+		ORDER BY net DESC
+	`
+	rows, err := repo.db.Query(query, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.PaymentMethodSummary, 0)
+	for rows.Next() {
+		var s models.PaymentMethodSummary
+		err := rows.Scan(&s.Metode, &s.Total, &s.JumlahTransaksi)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetTaxReport - rekap pajak dan service charge per tarif dan per hari (tanggal transaksi). Transaksi void
+// tidak dihitung dan pajak baris yang di-refund dikurangkan, sama seperti total pajak laporan penjualan.
+func (repo *ReportRepository) GetTaxReport(startDate, endDate string) (*models.TaxReport, error) {
+	report := &models.TaxReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		PerTarif:  make([]models.TaxRateSummary, 0),
+		PerHari:   make([]models.TaxDailySummary, 0),
+	}
+
+	// DPP = nilai setelah diskon, tanpa pajak (harga inklusif dikurangi pajaknya), setelah dikurangi refund
+	rateQuery := `
+		WITH ` + netTaxLinesCTE + `
+		SELECT tax_rate, COALESCE(SUM(dpp), 0)::bigint, COALESCE(SUM(pajak), 0)::bigint
+		FROM net_tax_lines
+		GROUP BY tax_rate
+		ORDER BY tax_rate
+	`
+	rows, err := repo.db.Query(rateQuery, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.TaxRateSummary
+		err := rows.Scan(&s.Tarif, &s.DPP, &s.Pajak)
+		if err != nil {
+			return nil, err
+		}
+		report.PerTarif = append(report.PerTarif, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dailyQuery := `
+		WITH ` + netTaxLinesCTE + `, daily_tax AS (
+			SELECT tanggal, SUM(dpp) AS dpp, SUM(pajak) AS pajak
+			FROM net_tax_lines
+			GROUP BY tanggal
+		)
+		SELECT TO_CHAR(DATE(t.created_at), 'YYYY-MM-DD'),
+			COALESCE(MAX(x.dpp), 0)::bigint, COALESCE(MAX(x.pajak), 0)::bigint,
+			COALESCE(SUM(t.service_charge_amount), 0)
+		FROM transactions t
+		LEFT JOIN daily_tax x ON x.tanggal = DATE(t.created_at)
+		WHERE DATE(t.created_at) >= $1 AND DATE(t.created_at) <= $2
+		  AND t.status <> 'voided'
+		GROUP BY DATE(t.created_at)
+		ORDER BY DATE(t.created_at)
+	`
+	dailyRows, err := repo.db.Query(dailyQuery, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer dailyRows.Close()
+
+	for dailyRows.Next() {
+		var s models.TaxDailySummary
+		err := dailyRows.Scan(&s.Tanggal, &s.DPP, &s.Pajak, &s.ServiceCharge)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalDPP += s.DPP
+		report.TotalPajak += s.Pajak
+		report.TotalServiceCharge += s.ServiceCharge
+		report.PerHari = append(report.PerHari, s)
+	}
+
+	return report, dailyRows.Err()
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"kasir-api/models"
+	"testing"
+	"time"
+)
+
+// TestReportsExcludeVoidedSales - transaksi void tidak boleh masuk angka penjualan / pajak di laporan
+// mana pun, dan refund-nya tidak ikut dikurangkan; laporan per kasir, Z-report, laporan penjualan
+// dan laporan pajak harus sama untuk periode yang sama. Laci kas tetap mencatat uang keluar masuk.
+func TestReportsExcludeVoidedSales(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	shifts := NewShiftRepository(db)
+	transactions := NewTransactionRepository(db)
+	reports := NewReportRepository(db)
+
+	cashier := &models.User{Username: "kasir", PasswordHash: "-", Role: models.RoleCashier, IsActive: true}
+	if err := users.Create(cashier); err != nil {
+		t.Fatal(err)
+	}
+	shift, err := shifts.Open(cashier.ID, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var productID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Produk Laporan', 10000, 10) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	actor := models.Actor{UserID: cashier.ID}
+	checkout := func(qty int) *models.Transaction {
+		t.Helper()
+		trx, err := transactions.CreateTransaction(models.NewTransaction{
+			Items: []models.CheckoutItem{{ProductID: productID, Quantity: qty}},
+			Tax:   models.TaxSettings{Rate: 11},
+			Actor: actor,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trx
+	}
+
+	// Penjualan 2 x 10.000 + PPN 11% = 22.200, satu unit di-refund (11.100)
+	kept := checkout(2)
+	if _, err := transactions.CreateRefund(kept.ID, models.RefundTypeRefund, "rusak",
+		[]models.RefundItem{{TransactionDetailID: kept.Details[0].ID, Quantity: 1}}, actor); err != nil {
+		t.Fatal(err)
+	}
+	// Penjualan 11.100 yang di-void
+	voided := checkout(1)
+	if _, err := transactions.CreateRefund(voided.ID, models.RefundTypeVoid, "salah input", nil, actor); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	end := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	sales, err := reports.GetSalesReportByDateRange(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sales.TotalTransaksi != 1 || sales.GrossRevenue != 20000 || sales.TotalPajak != 1100 ||
+		sales.TotalRefund != 11100 || sales.TotalRevenue != 11100 {
+		t.Errorf("laporan penjualan = %+v", sales)
+	}
+
+	tax, err := reports.GetTaxReport(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tax.TotalPajak != sales.TotalPajak {
+		t.Errorf("laporan pajak total_pajak = %d, laporan penjualan = %d", tax.TotalPajak, sales.TotalPajak)
+	}
+
+	perCashier, err := reports.GetSalesByCashier(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perCashier) != 1 {
+		t.Fatalf("laporan per kasir = %+v, want 1 kasir", perCashier)
+	}
+	c := perCashier[0]
+	if c.CashierID != cashier.ID || c.TotalTransaksi != sales.TotalTransaksi || c.GrossRevenue != sales.GrossRevenue ||
+		c.TotalPajak != sales.TotalPajak || c.TotalRefund != sales.TotalRefund || c.TotalRevenue != sales.TotalRevenue {
+		t.Errorf("laporan per kasir = %+v, laporan penjualan = %+v", c, sales)
+	}
+	if c.TotalTunai != 22200 {
+		t.Errorf("laporan per kasir total_tunai = %d, want 22200", c.TotalTunai)
+	}
+
+	z, err := reports.GetShiftReport(shift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.TotalTransaksi != 1 || z.GrossRevenue != 20000 || z.TotalPajak != 1100 ||
+		z.TotalPenjualan != 22200 || z.TotalRefund != 11100 {
+		t.Errorf("Z-report = %+v", z)
+	}
+	if len(z.PerMetodePembayaran) != 1 || z.PerMetodePembayaran[0].Total != 22200 {
+		t.Errorf("Z-report per metode = %+v, want tunai 22200", z.PerMetodePembayaran)
+	}
+	// Laci: dua penjualan tunai masuk, refund dan void keluar
+	if z.Kas.PenjualanTunai != 33300 || z.Kas.RefundTunai != 22200 || z.Kas.KasDiharapkan != 11100 {
+		t.Errorf("Z-report kas = %+v", z.Kas)
+	}
+}
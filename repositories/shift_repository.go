@@ -0,0 +1,305 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"strings"
+)
+
+var (
+	ErrShiftNotFound      = errors.New("shift tidak ditemukan")
+	ErrNoOpenShift        = errors.New("tidak ada shift yang sedang dibuka")
+	ErrShiftAlreadyOpened = errors.New("user masih punya shift yang terbuka")
+)
+
+type ShiftRepository struct {
+	db *sql.DB
+}
+
+func NewShiftRepository(db *sql.DB) *ShiftRepository {
+	return &ShiftRepository{db: db}
+}
+
+// queryRower - dipenuhi *sql.DB dan *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+const shiftColumns = `s.id, s.user_id, COALESCE(NULLIF(u.name, ''), u.username), s.terminal_id, s.opening_float, s.status,
+	s.opened_at, s.closed_at, s.counted_cash, s.expected_cash, s.over_short, s.note`
+
+func scanShift(scanner interface{ Scan(...interface{}) error }) (*models.Shift, error) {
+	var sh models.Shift
+	var terminalID sql.NullString
+	var closedAt sql.NullTime
+	var countedCash, expectedCash, overShort sql.NullInt64
+	err := scanner.Scan(&sh.ID, &sh.UserID, &sh.UserName, &terminalID, &sh.OpeningFloat, &sh.Status,
+		&sh.OpenedAt, &closedAt, &countedCash, &expectedCash, &overShort, &sh.Note)
+	if err != nil {
+		return nil, err
+	}
+	sh.TerminalID = terminalID.String
+	if closedAt.Valid {
+		sh.ClosedAt = &closedAt.Time
+	}
+	if countedCash.Valid {
+		v := int(countedCash.Int64)
+		sh.CountedCash = &v
+	}
+	if expectedCash.Valid {
+		v := int(expectedCash.Int64)
+		sh.ExpectedCash = &v
+	}
+	if overShort.Valid {
+		v := int(overShort.Int64)
+		sh.OverShort = &v
+	}
+	return &sh, nil
+}
+
+func (repo *ShiftRepository) Open(userID int, terminalID string, openingFloat int) (*models.Shift, error) {
+	var id int
+	err := repo.db.QueryRow(
+		"INSERT INTO shifts (user_id, terminal_id, opening_float) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING RETURNING id",
+		userID, nullableString(terminalID), openingFloat,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrShiftAlreadyOpened
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByID(id)
+}
+
+func (repo *ShiftRepository) GetByID(id int) (*models.Shift, error) {
+	sh, err := scanShift(repo.db.QueryRow(
+		"SELECT "+shiftColumns+" FROM shifts s JOIN users u ON s.user_id = u.id WHERE s.id = $1", id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrShiftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sh.CashMovements, err = repo.getCashMovements(id)
+	if err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// GetOpenByUser - shift user yang sedang terbuka
+func (repo *ShiftRepository) GetOpenByUser(userID int) (*models.Shift, error) {
+	var id int
+	err := repo.db.QueryRow("SELECT id FROM shifts WHERE user_id = $1 AND status = 'open'", userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenShift
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByID(id)
+}
+
+func (repo *ShiftRepository) GetAll(filter models.ShiftFilter) ([]models.Shift, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.UserID > 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("s.user_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("s.status = $%d", len(args)))
+	}
+	if filter.StartDate != "" {
+		args = append(args, filter.StartDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(s.opened_at) >= $%d", len(args)))
+	}
+	if filter.EndDate != "" {
+		args = append(args, filter.EndDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(s.opened_at) <= $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := repo.db.Query("SELECT "+shiftColumns+" FROM shifts s JOIN users u ON s.user_id = u.id"+where+
+		" ORDER BY s.opened_at DESC, s.id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := make([]models.Shift, 0)
+	for rows.Next() {
+		sh, err := scanShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, *sh)
+	}
+
+	return shifts, rows.Err()
+}
+
+// AddCashMovement - catat pay-in/payout pada shift user yang sedang terbuka
+func (repo *ShiftRepository) AddCashMovement(userID int, movementType string, amount int, reason string) (*models.CashMovement, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shiftID, err := lockOpenShift(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if shiftID == 0 {
+		return nil, ErrNoOpenShift
+	}
+
+	m := &models.CashMovement{
+		ShiftID: shiftID,
+		Type:    movementType,
+		Amount:  amount,
+		Reason:  reason,
+		UserID:  userID,
+	}
+	err = tx.QueryRow(
+		"INSERT INTO cash_movements (shift_id, type, amount, reason, user_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		shiftID, movementType, amount, reason, userID,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close - tutup shift user yang sedang terbuka. Baris shift dikunci FOR UPDATE supaya
+// checkout/refund yang sedang berjalan selesai dulu sebelum kas diharapkan dihitung.
+func (repo *ShiftRepository) Close(userID, countedCash int, note string) (*models.Shift, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var shiftID int
+	err = tx.QueryRow("SELECT id FROM shifts WHERE user_id = $1 AND status = 'open' FOR UPDATE", userID).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenShift
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cash, err := getShiftCashSummary(tx, shiftID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE shifts SET status = 'closed', closed_at = CURRENT_TIMESTAMP,
+			counted_cash = $1, expected_cash = $2, over_short = $3, note = $4
+		WHERE id = $5`,
+		countedCash, cash.KasDiharapkan, countedCash-cash.KasDiharapkan, note, shiftID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetByID(shiftID)
+}
+
+func (repo *ShiftRepository) getCashMovements(shiftID int) ([]models.CashMovement, error) {
+	rows, err := repo.db.Query(
+		"SELECT id, shift_id, type, amount, reason, user_id, created_at FROM cash_movements WHERE shift_id = $1 ORDER BY id",
+		shiftID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.CashMovement, 0)
+	for rows.Next() {
+		var m models.CashMovement
+		var userID sql.NullInt64
+		err := rows.Scan(&m.ID, &m.ShiftID, &m.Type, &m.Amount, &m.Reason, &userID, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.UserID = int(userID.Int64)
+		movements = append(movements, m)
+	}
+
+	return movements, rows.Err()
+}
+
+// lockOpenShift - ID shift terbuka milik user (0 jika tidak ada), dikunci FOR SHARE supaya
+// shift tidak bisa ditutup sebelum DB transaction pemanggil selesai
+func lockOpenShift(tx *sql.Tx, userID int) (int, error) {
+	if userID <= 0 {
+		return 0, nil
+	}
+	var shiftID int
+	err := tx.QueryRow("SELECT id FROM shifts WHERE user_id = $1 AND status = 'open' FOR SHARE", userID).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return shiftID, err
+}
+
+// getShiftCashSummary - kas diharapkan = modal awal + penjualan tunai + pay-in - payout - refund tunai.
+// Refund tunai adalah porsi tunai dari total transaksi asal, dikalikan nominal refund.
+func getShiftCashSummary(q queryRower, shiftID int) (*models.ShiftCashSummary, error) {
+	var c models.ShiftCashSummary
+	err := q.QueryRow(`
+		SELECT s.opening_float,
+			COALESCE((
+				SELECT SUM(p.amount) FROM payments p
+				JOIN transactions t ON p.transaction_id = t.id
+				WHERE t.shift_id = s.id AND p.method = 'cash'
+			), 0),
+			COALESCE((
+				SELECT SUM(ROUND(r.total_amount::numeric * cp.cash / NULLIF(t.total_amount, 0)))::bigint
+				FROM refunds r
+				JOIN transactions t ON r.transaction_id = t.id
+				JOIN (
+					SELECT transaction_id, SUM(amount) AS cash
+					FROM payments
+					WHERE method = 'cash'
+					GROUP BY transaction_id
+				) cp ON cp.transaction_id = t.id
+				WHERE r.shift_id = s.id
+			), 0),
+			COALESCE((SELECT SUM(amount) FROM cash_movements WHERE shift_id = s.id AND type = 'pay_in'), 0),
+			COALESCE((SELECT SUM(amount) FROM cash_movements WHERE shift_id = s.id AND type = 'payout'), 0)
+		FROM shifts s
+		WHERE s.id = $1`, shiftID,
+	).Scan(&c.OpeningFloat, &c.PenjualanTunai, &c.RefundTunai, &c.PayIn, &c.Payout)
+	if err == sql.ErrNoRows {
+		return nil, ErrShiftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.KasDiharapkan = c.OpeningFloat + c.PenjualanTunai + c.PayIn - c.Payout - c.RefundTunai
+	return &c, nil
+}
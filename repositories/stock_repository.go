@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"strings"
+)
+
+// ErrInsufficientStock - mutasi akan membuat stok negatif
+var ErrInsufficientStock = errors.New("stock tidak cukup")
+
+type StockRepository struct {
+	db *sql.DB
+}
+
+func NewStockRepository(db *sql.DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// Record - catat mutasi stok manual (adjustment/transfer) untuk satu produk
+func (repo *StockRepository) Record(m *models.StockMovement) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := applyStockMovement(tx, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetHistory - riwayat mutasi stok terbaru lebih dulu, beserta total data untuk pagination
+func (repo *StockRepository) GetHistory(filter models.StockMovementFilter) ([]models.StockMovement, int, error) {
+	conditions := []string{"product_id = $1"}
+	args := []interface{}{filter.ProductID}
+
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if filter.StartDate != "" {
+		args = append(args, filter.StartDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(created_at) >= $%d", len(args)))
+	}
+	if filter.EndDate != "" {
+		args = append(args, filter.EndDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(created_at) <= $%d", len(args)))
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM stock_movements"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, product_id, type, quantity, stock_after, reference_type, reference_id, note, user_id, created_at
+		FROM stock_movements` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.StockMovement, 0)
+	for rows.Next() {
+		var m models.StockMovement
+		var referenceType sql.NullString
+		var referenceID, userID sql.NullInt64
+		err := rows.Scan(&m.ID, &m.ProductID, &m.Type, &m.Quantity, &m.StockAfter, &referenceType, &referenceID,
+			&m.Note, &userID, &m.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		m.ReferenceType = referenceType.String
+		m.ReferenceID = int(referenceID.Int64)
+		m.UserID = int(userID.Int64)
+		movements = append(movements, m)
+	}
+
+	return movements, total, rows.Err()
+}
+
+// applyStockMovement - ubah products.stock dan tulis baris ledger di DB transaction yang sama.
+// Stok tidak boleh menjadi negatif; ErrInsufficientStock jika mutasi keluar melebihi stok,
+// ErrProductNotFound jika produknya tidak ada, ErrProductHasVariants untuk produk induk yang punya varian.
+// Jika mutasi membuat stok melewati min_stock ke bawah, notifikasi stok menipis ikut ditulis.
+func applyStockMovement(tx *sql.Tx, m *models.StockMovement) error {
+	var productName string
+	var minStock int
+	err := tx.QueryRow(
+		`UPDATE products SET stock = stock + $1
+		WHERE id = $2 AND stock + $1 >= 0 AND NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = products.id)
+		RETURNING stock, name, min_stock`,
+		m.Quantity, m.ProductID,
+	).Scan(&m.StockAfter, &productName, &minStock)
+	if err == sql.ErrNoRows {
+		var exists, hasVariants bool
+		err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM products WHERE id = $1), EXISTS (SELECT 1 FROM products WHERE parent_id = $1)",
+			m.ProductID,
+		).Scan(&exists, &hasVariants)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrProductNotFound
+		}
+		if hasVariants {
+			return ErrProductHasVariants
+		}
+		return ErrInsufficientStock
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO stock_movements (product_id, type, quantity, stock_after, reference_type, reference_id, note, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		m.ProductID, m.Type, m.Quantity, m.StockAfter, nullableString(m.ReferenceType), nullableID(m.ReferenceID),
+		m.Note, nullableID(m.UserID),
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// Hanya saat melewati ambang, supaya penjualan berikutnya di bawah ambang tidak mengirim ulang
+	stockBefore := m.StockAfter - m.Quantity
+	if minStock > 0 && stockBefore > minStock && m.StockAfter <= minStock {
+		return insertNotification(tx, models.NotificationLowStock,
+			fmt.Sprintf("Stok %s menipis: sisa %d (minimum %d)", productName, m.StockAfter, minStock),
+			models.LowStockEvent{
+				ProductID:     m.ProductID,
+				ProductName:   productName,
+				Stock:         m.StockAfter,
+				MinStock:      minStock,
+				MovementType:  m.Type,
+				ReferenceType: m.ReferenceType,
+				ReferenceID:   m.ReferenceID,
+			})
+	}
+	return nil
+}
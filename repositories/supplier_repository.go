@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+)
+
+var (
+	ErrSupplierNotFound = errors.New("supplier tidak ditemukan")
+	ErrSupplierInUse    = errors.New("supplier masih dipakai purchase order dan tidak bisa dihapus")
+)
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+func (repo *SupplierRepository) GetAll() ([]models.Supplier, error) {
+	query := "SELECT id, name, contact_name, phone, email, address, note, created_at FROM suppliers ORDER BY name, id"
+	rows, err := repo.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := make([]models.Supplier, 0)
+	for rows.Next() {
+		var s models.Supplier
+		err := rows.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address, &s.Note, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+
+	return suppliers, rows.Err()
+}
+
+func (repo *SupplierRepository) Create(supplier *models.Supplier) error {
+	query := `INSERT INTO suppliers (name, contact_name, phone, email, address, note)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	return repo.db.QueryRow(query, supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email,
+		supplier.Address, supplier.Note).Scan(&supplier.ID, &supplier.CreatedAt)
+}
+
+func (repo *SupplierRepository) GetByID(id int) (*models.Supplier, error) {
+	query := "SELECT id, name, contact_name, phone, email, address, note, created_at FROM suppliers WHERE id = $1"
+
+	var s models.Supplier
+	err := repo.db.QueryRow(query, id).Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address, &s.Note, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (repo *SupplierRepository) Update(supplier *models.Supplier) error {
+	query := `UPDATE suppliers SET name = $1, contact_name = $2, phone = $3, email = $4, address = $5, note = $6
+		WHERE id = $7 RETURNING created_at`
+	err := repo.db.QueryRow(query, supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email,
+		supplier.Address, supplier.Note, supplier.ID).Scan(&supplier.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrSupplierNotFound
+	}
+	return err
+}
+
+// Delete - supplier yang sudah punya purchase order tidak bisa dihapus supaya riwayat pembelian tetap utuh
+func (repo *SupplierRepository) Delete(id int) error {
+	var inUse bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM purchase_orders WHERE supplier_id = $1)", id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrSupplierInUse
+	}
+
+	result, err := repo.db.Exec("DELETE FROM suppliers WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrSupplierNotFound
+	}
+
+	return nil
+}
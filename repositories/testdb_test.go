@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"database/sql"
+	"kasir-api/testdb"
+	"testing"
+)
+
+// openTestDB - schema Postgres baru yang sudah dimigrasi; di-skip jika testdb.Env kosong
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	return testdb.Open(t)
+}
@@ -2,10 +2,28 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"kasir-api/models"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrTransactionNotFound - transaksi dengan ID tersebut tidak ada
+var ErrTransactionNotFound = errors.New("transaksi tidak ditemukan")
+
+// ErrIdempotencyKeyExists - Idempotency-Key sudah tersimpan oleh checkout lain
+var ErrIdempotencyKeyExists = errors.New("idempotency key sudah dipakai")
+
+// ErrInvalidRefund - void/refund ditolak karena status transaksi, item, atau quantity yang diminta
+var ErrInvalidRefund = errors.New("void/refund tidak valid")
+
 type TransactionRepository struct {
 	db *sql.DB
 }
@@ -14,50 +32,161 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (*models.Transaction, error) {
+// CreateTransaction - simpan transaksi dan kurangi stok produk.
+// Baris produk dikunci (FOR UPDATE) berurutan by ID supaya checkout paralel
+// dari beberapa kasir tidak oversell dan tidak saling deadlock.
+// Jika input.Idempotency tidak nil, key dan response disimpan di DB transaction yang sama.
+func (repo *TransactionRepository) CreateTransaction(input models.NewTransaction) (*models.Transaction, error) {
+	items := input.Items
+	idempotency := input.Idempotency
+
 	tx, err := repo.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	totalAmount := 0
-	details := make([]models.TransactionDetail, 0)
+	// Klaim key lebih dulu; retry paralel dengan key sama akan menunggu di sini sampai checkout pertama selesai
+	if idempotency != nil {
+		result, err := tx.Exec(
+			"INSERT INTO idempotency_keys (cashier_id, key, request_hash) VALUES ($1, $2, $3) ON CONFLICT (cashier_id, key) DO NOTHING",
+			idempotency.CashierID, idempotency.Key, idempotency.RequestHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, ErrIdempotencyKeyExists
+		}
+	}
+
+	// Override harga hanya dipakai jika ada harga manual; berlaku sekali dan ikut batal jika checkout gagal
+	actor := models.Actor{UserID: input.Actor.UserID, TerminalID: input.Actor.TerminalID}
+	if slices.ContainsFunc(items, func(item models.CheckoutItem) bool { return item.UnitPrice != nil }) {
+		actor = input.Actor.OverrideFor(models.PermPriceOverride)
+	}
+	if err := consumeOverride(tx, actor, models.PermPriceOverride, 0); err != nil {
+		return nil, err
+	}
 
+	quantities := make(map[int]int)
+	productIDs := make([]int64, 0, len(items))
 	for _, item := range items {
-		var productPrice, stock int
-		var productName string
+		if _, ok := quantities[item.ProductID]; !ok {
+			productIDs = append(productIDs, int64(item.ProductID))
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	products, err := lockProducts(tx, productIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		err := tx.QueryRow("SELECT name, price, stock FROM products WHERE id = $1", item.ProductID).Scan(&productName, &productPrice, &stock)
-		if err == sql.ErrNoRows {
+	subtotalAmount := 0
+	discountAmount := 0
+	taxAmount := 0
+	details := make([]models.TransactionDetail, 0)
+
+	for _, item := range items {
+		p, ok := products[item.ProductID]
+		if !ok {
 			return nil, fmt.Errorf("product id %d not found", item.ProductID)
 		}
-		if err != nil {
-			return nil, err
+
+		if p.archived {
+			return nil, fmt.Errorf("produk %s sudah diarsipkan dan tidak bisa dijual", p.name)
+		}
+		if p.hasVariants {
+			return nil, fmt.Errorf("produk %s punya varian, pilih salah satu varian", p.name)
+		}
+		if p.stock < quantities[item.ProductID] {
+			return nil, fmt.Errorf("stock produk %s tidak cukup (tersedia: %d, diminta: %d)", p.name, p.stock, quantities[item.ProductID])
 		}
 
-		if stock < item.Quantity {
-			return nil, fmt.Errorf("stock produk %s tidak cukup (tersedia: %d, diminta: %d)", productName, stock, item.Quantity)
+		unitPrice := p.price
+		if item.UnitPrice != nil {
+			unitPrice = *item.UnitPrice
 		}
 
-		subtotal := productPrice * item.Quantity
-		totalAmount += subtotal
+		detail := models.TransactionDetail{
+			ProductID:    item.ProductID,
+			ProductName:  p.name,
+			CategoryName: p.categoryName.String,
+			UnitPrice:    unitPrice,
+			UnitCost:     p.costPrice,
+			Quantity:     item.Quantity,
+		}
+		if p.categoryID.Valid {
+			detail.CategoryID = int(p.categoryID.Int64)
+		}
+		if p.parentID.Valid {
+			detail.ParentProductID = int(p.parentID.Int64)
+		}
 
-		_, err = tx.Exec("UPDATE products SET stock = stock - $1 WHERE id = $2", item.Quantity, item.ProductID)
-		if err != nil {
-			return nil, err
+		gross := unitPrice * item.Quantity
+		promo, discount := bestPromotion(input.Promotions, detail)
+		if promo != nil {
+			detail.PromotionID = promo.ID
+			detail.DiscountAmount = discount
 		}
+		detail.Subtotal = gross - detail.DiscountAmount
 
-		details = append(details, models.TransactionDetail{
-			ProductID:   item.ProductID,
-			ProductName: productName,
-			Quantity:    item.Quantity,
-			Subtotal:    subtotal,
-		})
+		// Tarif produk menimpa tarif kategori, tarif kategori menimpa default
+		detail.TaxRate = input.Tax.Rate
+		if p.categoryTaxRate.Valid {
+			detail.TaxRate = p.categoryTaxRate.Float64
+		}
+		if p.taxRate.Valid {
+			detail.TaxRate = p.taxRate.Float64
+		}
+		detail.TaxAmount = input.Tax.TaxFor(detail.Subtotal, detail.TaxRate)
+
+		subtotalAmount += gross
+		discountAmount += detail.DiscountAmount
+		taxAmount += detail.TaxAmount
+		details = append(details, detail)
+	}
+
+	// Service charge dihitung dari nilai setelah diskon sebelum pajak
+	serviceBase := subtotalAmount - discountAmount
+	if input.Tax.Inclusive {
+		serviceBase -= taxAmount
+	}
+	serviceChargeAmount := int(math.Round(float64(serviceBase) * input.Tax.ServiceChargeRate / 100))
+
+	totalAmount := subtotalAmount - discountAmount + serviceChargeAmount
+	if !input.Tax.Inclusive {
+		totalAmount += taxAmount
+	}
+
+	payments, paidAmount, changeAmount, err := settlePayments(totalAmount, input.Payments)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftID, err := lockOpenShift(tx, input.Actor.UserID)
+	if err != nil {
+		return nil, err
 	}
 
 	var transactionID int
-	err = tx.QueryRow("INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id", totalAmount).Scan(&transactionID)
+	var createdAt time.Time
+	var cashierName sql.NullString
+	err = tx.QueryRow(
+		`INSERT INTO transactions (subtotal_amount, discount_amount, tax_amount, service_charge_amount, tax_inclusive,
+			total_amount, paid_amount, change_amount, cashier_id, terminal_id, approved_by, shift_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, (SELECT COALESCE(NULLIF(name, ''), username) FROM users WHERE id = $9)`,
+		subtotalAmount, discountAmount, taxAmount, serviceChargeAmount, input.Tax.Inclusive,
+		totalAmount, paidAmount, changeAmount,
+		nullableID(actor.UserID), nullableString(actor.TerminalID), nullableID(actor.ApprovedBy), nullableID(shiftID),
+	).Scan(&transactionID, &createdAt, &cashierName)
 	if err != nil {
 		return nil, err
 	}
@@ -66,8 +195,13 @@ func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem
 		details[i].TransactionID = transactionID
 		var detailID int
 		err = tx.QueryRow(
-			"INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal) VALUES ($1, $2, $3, $4) RETURNING id",
-			transactionID, details[i].ProductID, details[i].Quantity, details[i].Subtotal,
+			`INSERT INTO transaction_details
+				(transaction_id, product_id, parent_product_id, product_name, category_id, category_name, unit_price, unit_cost, quantity,
+				subtotal, discount_amount, promotion_id, tax_rate, tax_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+			transactionID, details[i].ProductID, nullableID(details[i].ParentProductID), details[i].ProductName, nullableID(details[i].CategoryID), details[i].CategoryName,
+			details[i].UnitPrice, details[i].UnitCost, details[i].Quantity, details[i].Subtotal, details[i].DiscountAmount, nullableID(details[i].PromotionID),
+			details[i].TaxRate, details[i].TaxAmount,
 		).Scan(&detailID)
 		if err != nil {
 			return nil, err
@@ -75,13 +209,770 @@ func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem
 		details[i].ID = detailID
 	}
 
+	// Kurangi stok lewat ledger mengikuti urutan lock; cek stok di ledger sebagai pengaman terakhir
+	for _, id := range productIDs {
+		err := applyStockMovement(tx, &models.StockMovement{
+			ProductID:     int(id),
+			Type:          models.StockMovementSale,
+			Quantity:      -quantities[int(id)],
+			ReferenceType: models.StockReferenceTransaction,
+			ReferenceID:   transactionID,
+			UserID:        input.Actor.UserID,
+		})
+		if errors.Is(err, ErrInsufficientStock) {
+			return nil, fmt.Errorf("stock produk %s tidak cukup", products[int(id)].name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range payments {
+		payments[i].TransactionID = transactionID
+		var reference interface{}
+		if payments[i].Reference != "" {
+			reference = payments[i].Reference
+		}
+		err = tx.QueryRow(
+			`INSERT INTO payments (transaction_id, method, amount, tendered, change_amount, reference)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+			transactionID, payments[i].Method, payments[i].Amount, payments[i].Tendered, payments[i].ChangeAmount, reference,
+		).Scan(&payments[i].ID, &payments[i].CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transaction := &models.Transaction{
+		ID:                  transactionID,
+		SubtotalAmount:      subtotalAmount,
+		DiscountAmount:      discountAmount,
+		TaxAmount:           taxAmount,
+		ServiceChargeAmount: serviceChargeAmount,
+		TaxInclusive:        input.Tax.Inclusive,
+		TotalAmount:         totalAmount,
+		PaidAmount:          paidAmount,
+		ChangeAmount:        changeAmount,
+		Status:              models.TransactionStatusCompleted,
+		CashierID:           input.Actor.UserID,
+		CashierName:         cashierName.String,
+		TerminalID:          input.Actor.TerminalID,
+		ApprovedBy:          actor.ApprovedBy,
+		ShiftID:             shiftID,
+		CreatedAt:           createdAt,
+		Details:             details,
+		Payments:            payments,
+	}
+
+	if idempotency != nil {
+		response, err := json.Marshal(transaction)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("UPDATE idempotency_keys SET transaction_id = $1, response = $2 WHERE cashier_id = $3 AND key = $4",
+			transactionID, response, idempotency.CashierID, idempotency.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// GetIdempotencyRecord - ambil Idempotency-Key milik kasir cashierID yang dibuat setelah since, nil jika tidak ada
+func (repo *TransactionRepository) GetIdempotencyRecord(cashierID int, key string, since time.Time) (*models.IdempotencyRecord, error) {
+	var rec models.IdempotencyRecord
+	var transactionID sql.NullInt64
+	err := repo.db.QueryRow(
+		`SELECT cashier_id, key, request_hash, transaction_id, response, created_at FROM idempotency_keys
+		WHERE cashier_id = $1 AND key = $2 AND created_at >= $3`,
+		cashierID, key, since,
+	).Scan(&rec.CashierID, &rec.Key, &rec.RequestHash, &transactionID, &rec.Response, &rec.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if transactionID.Valid {
+		rec.TransactionID = int(transactionID.Int64)
+	}
+
+	return &rec, nil
+}
+
+// DeleteExpiredIdempotencyKeys - hapus Idempotency-Key yang sudah lewat retention window
+func (repo *TransactionRepository) DeleteExpiredIdempotencyKeys(before time.Time) error {
+	_, err := repo.db.Exec("DELETE FROM idempotency_keys WHERE created_at < $1", before)
+	return err
+}
+
+// GetAll - ambil daftar transaksi sesuai filter, beserta total data untuk pagination
+func (repo *TransactionRepository) GetAll(filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.StartDate != "" {
+		args = append(args, filter.StartDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(t.created_at) >= $%d", len(args)))
+	}
+	if filter.EndDate != "" {
+		args = append(args, filter.EndDate)
+		conditions = append(conditions, fmt.Sprintf("DATE(t.created_at) <= $%d", len(args)))
+	}
+	if filter.MinAmount > 0 {
+		args = append(args, filter.MinAmount)
+		conditions = append(conditions, fmt.Sprintf("t.total_amount >= $%d", len(args)))
+	}
+	if filter.MaxAmount > 0 {
+		args = append(args, filter.MaxAmount)
+		conditions = append(conditions, fmt.Sprintf("t.total_amount <= $%d", len(args)))
+	}
+	if filter.ProductID > 0 {
+		args = append(args, filter.ProductID)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM transaction_details td WHERE td.transaction_id = t.id AND td.product_id = $%d)", len(args)))
+	}
+	if filter.CashierID > 0 {
+		args = append(args, filter.CashierID)
+		conditions = append(conditions, fmt.Sprintf("t.cashier_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM transactions t"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions t LEFT JOIN users u ON t.cashier_id = u.id" + where +
+		fmt.Sprintf(" ORDER BY t.created_at DESC, t.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+	ids := make([]int64, 0)
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.Details = make([]models.TransactionDetail, 0)
+		transactions = append(transactions, *t)
+		ids = append(ids, int64(t.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if len(ids) == 0 {
+		return transactions, total, nil
+	}
+
+	details, err := repo.getDetails(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range transactions {
+		if d, ok := details[transactions[i].ID]; ok {
+			transactions[i].Details = d
+		}
+	}
+
+	return transactions, total, nil
+}
+
+// GetByID - ambil satu transaksi beserta detailnya
+func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
+	t, err := scanTransaction(repo.db.QueryRow(
+		"SELECT "+transactionColumns+" FROM transactions t LEFT JOIN users u ON t.cashier_id = u.id WHERE t.id = $1", id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := repo.getDetails([]int64{int64(id)})
+	if err != nil {
+		return nil, err
+	}
+	t.Details = details[id]
+	if t.Details == nil {
+		t.Details = make([]models.TransactionDetail, 0)
+	}
+
+	t.Payments, err = repo.getPayments(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Refunds, err = repo.getRefunds(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+const transactionColumns = `t.id, t.subtotal_amount, t.discount_amount, t.tax_amount, t.service_charge_amount, t.tax_inclusive,
+	t.total_amount, t.paid_amount, t.change_amount, t.status, t.cashier_id, COALESCE(NULLIF(u.name, ''), u.username),
+	t.terminal_id, t.approved_by, t.shift_id, t.created_at`
+
+func scanTransaction(scanner interface{ Scan(...interface{}) error }) (*models.Transaction, error) {
+	var t models.Transaction
+	var cashierID, approvedBy, shiftID sql.NullInt64
+	var cashierName, terminalID sql.NullString
+	err := scanner.Scan(&t.ID, &t.SubtotalAmount, &t.DiscountAmount, &t.TaxAmount, &t.ServiceChargeAmount, &t.TaxInclusive,
+		&t.TotalAmount, &t.PaidAmount, &t.ChangeAmount, &t.Status, &cashierID, &cashierName,
+		&terminalID, &approvedBy, &shiftID, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.CashierID = int(cashierID.Int64)
+	t.CashierName = cashierName.String
+	t.TerminalID = terminalID.String
+	t.ApprovedBy = int(approvedBy.Int64)
+	t.ShiftID = int(shiftID.Int64)
+	return &t, nil
+}
+
+// bestPromotion - pilih promo dengan diskon terbesar untuk satu baris; promo tidak ditumpuk
+func bestPromotion(promotions []models.Promotion, detail models.TransactionDetail) (*models.Promotion, int) {
+	var best *models.Promotion
+	bestDiscount := 0
+	for i := range promotions {
+		if !promotions[i].AppliesTo(detail.ProductID, detail.ParentProductID, detail.CategoryID) {
+			continue
+		}
+		discount := promotions[i].DiscountFor(detail.UnitPrice, detail.Quantity)
+		if discount > bestDiscount {
+			best = &promotions[i]
+			bestDiscount = discount
+		}
+	}
+	return best, bestDiscount
+}
+
+// settlePayments - alokasikan pembayaran ke total tagihan.
+// Non-tunai dialokasikan dulu dan tidak boleh melebihi sisa tagihan; kembalian hanya dari tunai.
+// Tanpa pembayaran, transaksi dianggap dibayar tunai pas.
+func settlePayments(total int, inputs []models.PaymentInput) ([]models.Payment, int, int, error) {
+	if len(inputs) == 0 {
+		inputs = []models.PaymentInput{{Method: models.PaymentMethodCash, Amount: total}}
+	}
+
+	payments := make([]models.Payment, len(inputs))
+	remaining := total
+	paidAmount := 0
+
+	for i, in := range inputs {
+		payments[i] = models.Payment{Method: in.Method, Tendered: in.Amount, Reference: in.Reference}
+		paidAmount += in.Amount
+		if in.Method == models.PaymentMethodCash {
+			continue
+		}
+		if in.Amount > remaining {
+			return nil, 0, 0, fmt.Errorf("pembayaran %s (%d) melebihi sisa tagihan (%d)", in.Method, in.Amount, remaining)
+		}
+		payments[i].Amount = in.Amount
+		remaining -= in.Amount
+	}
+
+	changeAmount := 0
+	for i := range payments {
+		if payments[i].Method != models.PaymentMethodCash {
+			continue
+		}
+		applied := payments[i].Tendered
+		if applied > remaining {
+			applied = remaining
+		}
+		payments[i].Amount = applied
+		payments[i].ChangeAmount = payments[i].Tendered - applied
+		changeAmount += payments[i].ChangeAmount
+		remaining -= applied
+	}
+
+	if remaining > 0 {
+		return nil, 0, 0, fmt.Errorf("pembayaran kurang (total: %d, dibayar: %d)", total, paidAmount)
+	}
+
+	return payments, paidAmount, changeAmount, nil
+}
+
+// lockedProduct - data produk yang sudah dikunci di dalam DB transaction checkout
+type lockedProduct struct {
+	name            string
+	price           int
+	costPrice       int
+	parentID        sql.NullInt64
+	hasVariants     bool
+	archived        bool
+	stock           int
+	categoryID      sql.NullInt64
+	categoryName    sql.NullString
+	taxRate         sql.NullFloat64
+	categoryTaxRate sql.NullFloat64
+}
+
+// lockProducts - kunci baris produk dengan urutan ID menaik; productIDs harus sudah terurut
+func lockProducts(tx *sql.Tx, productIDs []int64) (map[int]*lockedProduct, error) {
+	rows, err := tx.Query(`SELECT p.id, p.name, p.price, p.cost_price, p.stock, p.category_id, c.name, p.tax_rate, c.tax_rate,
+			p.parent_id, EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id), p.deleted_at IS NOT NULL
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ANY($1)
+		ORDER BY p.id
+		FOR UPDATE OF p`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make(map[int]*lockedProduct)
+	for rows.Next() {
+		var id int
+		p := &lockedProduct{}
+		err := rows.Scan(&id, &p.name, &p.price, &p.costPrice, &p.stock, &p.categoryID, &p.categoryName, &p.taxRate, &p.categoryTaxRate,
+			&p.parentID, &p.hasVariants, &p.archived)
+		if err != nil {
+			return nil, err
+		}
+		products[id] = p
+	}
+
+	return products, rows.Err()
+}
+
+// getDetails - ambil detail untuk sekumpulan transaksi, dikelompokkan per transaction_id
+func (repo *TransactionRepository) getDetails(transactionIDs []int64) (map[int][]models.TransactionDetail, error) {
+	query := `SELECT td.id, td.transaction_id, td.product_id, COALESCE(td.parent_product_id, 0), td.product_name, td.category_id,
+			  COALESCE(td.category_name, ''), td.unit_price, td.unit_cost, td.quantity, td.subtotal,
+			  td.discount_amount, td.promotion_id, td.tax_rate, td.tax_amount
+			  FROM transaction_details td
+			  WHERE td.transaction_id = ANY($1)
+			  ORDER BY td.id`
+
+	rows, err := repo.db.Query(query, pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	details := make(map[int][]models.TransactionDetail)
+	for rows.Next() {
+		var d models.TransactionDetail
+		var productID, categoryID, promotionID sql.NullInt64
+		err := rows.Scan(&d.ID, &d.TransactionID, &productID, &d.ParentProductID, &d.ProductName, &categoryID,
+			&d.CategoryName, &d.UnitPrice, &d.UnitCost, &d.Quantity, &d.Subtotal, &d.DiscountAmount, &promotionID,
+			&d.TaxRate, &d.TaxAmount)
+		if err != nil {
+			return nil, err
+		}
+		if promotionID.Valid {
+			d.PromotionID = int(promotionID.Int64)
+		}
+		if productID.Valid {
+			d.ProductID = int(productID.Int64)
+		}
+		if categoryID.Valid {
+			d.CategoryID = int(categoryID.Int64)
+		}
+		details[d.TransactionID] = append(details[d.TransactionID], d)
+	}
+
+	return details, rows.Err()
+}
+
+// getPayments - ambil semua pembayaran milik satu transaksi
+func (repo *TransactionRepository) getPayments(transactionID int) ([]models.Payment, error) {
+	rows, err := repo.db.Query(
+		`SELECT id, transaction_id, method, amount, tendered, change_amount, COALESCE(reference, ''), created_at
+		FROM payments WHERE transaction_id = $1 ORDER BY id`,
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]models.Payment, 0)
+	for rows.Next() {
+		var p models.Payment
+		err := rows.Scan(&p.ID, &p.TransactionID, &p.Method, &p.Amount, &p.Tendered, &p.ChangeAmount, &p.Reference, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// getRefunds - ambil semua void/refund milik satu transaksi beserta itemnya
+func (repo *TransactionRepository) getRefunds(transactionID int) ([]models.Refund, error) {
+	rows, err := repo.db.Query(
+		"SELECT id, transaction_id, type, reason, total_amount, user_id, approved_by, shift_id, created_at FROM refunds WHERE transaction_id = $1 ORDER BY id",
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := make([]models.Refund, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var rf models.Refund
+		var userID, approvedBy, shiftID sql.NullInt64
+		err := rows.Scan(&rf.ID, &rf.TransactionID, &rf.Type, &rf.Reason, &rf.TotalAmount, &userID, &approvedBy, &shiftID, &rf.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rf.UserID = int(userID.Int64)
+		rf.ApprovedBy = int(approvedBy.Int64)
+		rf.ShiftID = int(shiftID.Int64)
+		rf.Details = make([]models.RefundDetail, 0)
+		index[rf.ID] = len(refunds)
+		refunds = append(refunds, rf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(refunds) == 0 {
+		return refunds, nil
+	}
+
+	detailRows, err := repo.db.Query(`SELECT rd.id, rd.refund_id, rd.transaction_detail_id, rd.product_id, rd.quantity, rd.amount
+			  FROM refund_details rd
+			  JOIN refunds r ON rd.refund_id = r.id
+			  WHERE r.transaction_id = $1
+			  ORDER BY rd.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer detailRows.Close()
+
+	for detailRows.Next() {
+		var d models.RefundDetail
+		var productID sql.NullInt64
+		err := detailRows.Scan(&d.ID, &d.RefundID, &d.TransactionDetailID, &productID, &d.Quantity, &d.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if productID.Valid {
+			d.ProductID = int(productID.Int64)
+		}
+		i := index[d.RefundID]
+		refunds[i].Details = append(refunds[i].Details, d)
+	}
+
+	return refunds, detailRows.Err()
+}
+
+// refundableLine - sisa item transaksi yang masih bisa dikembalikan.
+// subtotal sudah termasuk pajak eksklusif dan porsi service charge baris tersebut.
+type refundableLine struct {
+	detailID       int
+	productID      sql.NullInt64
+	quantity       int
+	subtotal       int
+	serviceBase    int
+	refundedQty    int
+	refundedAmount int
+}
+
+func (l *refundableLine) remaining() int {
+	return l.quantity - l.refundedQty
+}
+
+// allocateServiceCharge - bagi service charge transaksi ke tiap baris sesuai dasar service charge-nya;
+// baris terakhir mengambil sisa pembulatan. Dengan begitu refund semua baris satu per satu mengembalikan
+// nominal yang sama dengan void.
+func allocateServiceCharge(lines []*refundableLine, serviceCharge int) {
+	totalBase := 0
+	for _, l := range lines {
+		totalBase += l.serviceBase
+	}
+	if serviceCharge <= 0 || totalBase <= 0 {
+		return
+	}
+	allocated := 0
+	for i, l := range lines {
+		share := serviceCharge * l.serviceBase / totalBase
+		if i == len(lines)-1 {
+			share = serviceCharge - allocated
+		}
+		l.subtotal += share
+		allocated += share
+	}
+}
+
+// refundShift - shift laci yang menanggung uang refund: shift terbuka milik user yang memproses, atau
+// shift transaksi asal jika masih terbuka (mis. supervisor yang tidak membuka shift sendiri). Refund
+// dengan porsi tunai harus masuk ke salah satunya supaya kas diharapkan shift ikut berkurang.
+func refundShift(tx *sql.Tx, transactionID, userID, saleShiftID, amount int) (int, error) {
+	shiftID, err := lockOpenShift(tx, userID)
+	if err != nil || shiftID > 0 {
+		return shiftID, err
+	}
+	if saleShiftID > 0 {
+		err := tx.QueryRow("SELECT id FROM shifts WHERE id = $1 AND status = 'open' FOR SHARE", saleShiftID).Scan(&shiftID)
+		if err == nil {
+			return shiftID, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	var cash int
+	err = tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE transaction_id = $1 AND method = 'cash'", transactionID).
+		Scan(&cash)
+	if err != nil {
+		return 0, err
+	}
+	if cash > 0 && amount > 0 {
+		return 0, fmt.Errorf("%w: %w, refund tunai harus dicatat di laci shift", ErrInvalidRefund, ErrNoOpenShift)
+	}
+	return 0, nil
+}
+
+// CreateRefund - simpan void/refund dan kembalikan stok dalam satu DB transaction.
+// Untuk void, items diabaikan dan seluruh sisa item transaksi dikembalikan.
+func (repo *TransactionRepository) CreateRefund(transactionID int, refundType, reason string, items []models.RefundItem, actor models.Actor) (*models.Refund, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Kunci baris transaksi supaya void/refund paralel tidak double-restore stok
+	var status string
+	var taxInclusive bool
+	var totalAmount, serviceCharge int
+	var saleShiftID sql.NullInt64
+	err = tx.QueryRow("SELECT status, tax_inclusive, total_amount, service_charge_amount, shift_id FROM transactions WHERE id = $1 FOR UPDATE", transactionID).
+		Scan(&status, &taxInclusive, &totalAmount, &serviceCharge, &saleShiftID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == models.TransactionStatusVoided || status == models.TransactionStatusRefunded {
+		return nil, fmt.Errorf("%w: transaksi sudah %s", ErrInvalidRefund, status)
+	}
+
+	perm := models.PermRefund
+	if refundType == models.RefundTypeVoid {
+		perm = models.PermVoid
+	}
+	actor = actor.OverrideFor(perm)
+	if err := consumeOverride(tx, actor, perm, transactionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT td.id, td.product_id, td.quantity, td.subtotal, td.tax_amount,
+			  COALESCE(SUM(rd.quantity), 0), COALESCE(SUM(rd.amount), 0)
+			  FROM transaction_details td
+			  LEFT JOIN refund_details rd ON rd.transaction_detail_id = td.id
+			  WHERE td.transaction_id = $1
+			  GROUP BY td.id
+			  ORDER BY td.id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]*refundableLine, 0)
+	lineByID := make(map[int]*refundableLine)
+	for rows.Next() {
+		l := &refundableLine{}
+		var lineTax int
+		err := rows.Scan(&l.detailID, &l.productID, &l.quantity, &l.subtotal, &lineTax, &l.refundedQty, &l.refundedAmount)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Pajak eksklusif ikut dikembalikan karena dibayar di atas subtotal; dasar service charge
+		// sama dengan saat checkout (setelah diskon, sebelum pajak)
+		l.serviceBase = l.subtotal
+		if taxInclusive {
+			l.serviceBase -= lineTax
+		} else {
+			l.subtotal += lineTax
+		}
+		lines = append(lines, l)
+		lineByID[l.detailID] = l
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	allocateServiceCharge(lines, serviceCharge)
+
+	requested := make(map[int]int)
+	if refundType == models.RefundTypeVoid {
+		for _, l := range lines {
+			if l.remaining() > 0 {
+				requested[l.detailID] = l.remaining()
+			}
+		}
+	} else {
+		for _, item := range items {
+			l, ok := lineByID[item.TransactionDetailID]
+			if !ok {
+				return nil, fmt.Errorf("%w: transaction_detail_id %d bukan bagian dari transaksi ini", ErrInvalidRefund, item.TransactionDetailID)
+			}
+			if item.Quantity <= 0 {
+				return nil, fmt.Errorf("%w: quantity untuk transaction_detail_id %d harus lebih dari 0", ErrInvalidRefund, item.TransactionDetailID)
+			}
+			requested[l.detailID] += item.Quantity
+		}
+	}
+
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: tidak ada item yang bisa dikembalikan", ErrInvalidRefund)
+	}
+
+	refund := &models.Refund{
+		TransactionID: transactionID,
+		Type:          refundType,
+		Reason:        reason,
+		UserID:        actor.UserID,
+		ApprovedBy:    actor.ApprovedBy,
+		Details:       make([]models.RefundDetail, 0),
+	}
+
+	fullyRefunded := true
+	for _, l := range lines {
+		qty := requested[l.detailID]
+		if qty > l.remaining() {
+			return nil, fmt.Errorf("%w: quantity refund untuk transaction_detail_id %d melebihi sisa (sisa: %d, diminta: %d)",
+				ErrInvalidRefund, l.detailID, l.remaining(), qty)
+		}
+		if qty < l.remaining() {
+			fullyRefunded = false
+		}
+		if qty == 0 {
+			continue
+		}
+
+		// Nominal proporsional; sisa terakhir mengambil semua sisa subtotal supaya tidak ada selisih pembulatan
+		amount := l.subtotal * qty / l.quantity
+		if qty == l.remaining() {
+			amount = l.subtotal - l.refundedAmount
+		}
+		refund.TotalAmount += amount
+
+		detail := models.RefundDetail{
+			TransactionDetailID: l.detailID,
+			Quantity:            qty,
+			Amount:              amount,
+		}
+		if l.productID.Valid {
+			detail.ProductID = int(l.productID.Int64)
+		}
+		refund.Details = append(refund.Details, detail)
+	}
+
+	// Void mengembalikan seluruh sisa grand total, termasuk service charge
+	if refundType == models.RefundTypeVoid {
+		var alreadyRefunded int
+		err = tx.QueryRow("SELECT COALESCE(SUM(total_amount), 0) FROM refunds WHERE transaction_id = $1", transactionID).
+			Scan(&alreadyRefunded)
+		if err != nil {
+			return nil, err
+		}
+		refund.TotalAmount = totalAmount - alreadyRefunded
+	}
+
+	refund.ShiftID, err = refundShift(tx, transactionID, actor.UserID, int(saleShiftID.Int64), refund.TotalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO refunds (transaction_id, type, reason, total_amount, user_id, approved_by, shift_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		transactionID, refund.Type, refund.Reason, refund.TotalAmount, nullableID(actor.UserID), nullableID(actor.ApprovedBy),
+		nullableID(refund.ShiftID),
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range refund.Details {
+		d := &refund.Details[i]
+		d.RefundID = refund.ID
+
+		err = tx.QueryRow(
+			"INSERT INTO refund_details (refund_id, transaction_detail_id, product_id, quantity, amount) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			refund.ID, d.TransactionDetailID, nullableID(d.ProductID), d.Quantity, d.Amount,
+		).Scan(&d.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Kembalikan stok berurutan by product ID, sama dengan urutan lock di CreateTransaction
+	restocks := make(map[int]int)
+	restockIDs := make([]int, 0)
+	for _, d := range refund.Details {
+		if d.ProductID <= 0 {
+			continue
+		}
+		if _, ok := restocks[d.ProductID]; !ok {
+			restockIDs = append(restockIDs, d.ProductID)
+		}
+		restocks[d.ProductID] += d.Quantity
+	}
+	sort.Ints(restockIDs)
+	for _, id := range restockIDs {
+		err = applyStockMovement(tx, &models.StockMovement{
+			ProductID:     id,
+			Type:          models.StockMovementRefund,
+			Quantity:      restocks[id],
+			ReferenceType: models.StockReferenceRefund,
+			ReferenceID:   refund.ID,
+			Note:          refund.Reason,
+			UserID:        actor.UserID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newStatus := models.TransactionStatusPartiallyRefunded
+	if refundType == models.RefundTypeVoid {
+		newStatus = models.TransactionStatusVoided
+	} else if fullyRefunded {
+		newStatus = models.TransactionStatusRefunded
+	}
+	_, err = tx.Exec("UPDATE transactions SET status = $1 WHERE id = $2", newStatus, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return &models.Transaction{
-		ID:          transactionID,
-		TotalAmount: totalAmount,
-		Details:     details,
-	}, nil
+	return refund, nil
 }
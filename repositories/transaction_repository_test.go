@@ -0,0 +1,450 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TestCreateTransactionConcurrentCheckout - checkout paralel dengan produk yang saling tumpang tindih
+// (urutan item berbeda-beda dan item dobel dalam satu keranjang) tidak boleh oversell, membuat stok
+// negatif, atau gagal karena deadlock. Satu-satunya kegagalan yang wajar adalah stok tidak cukup.
+func TestCreateTransactionConcurrentCheckout(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	const (
+		initialStock = 25
+		workers      = 30
+		rounds       = 5
+	)
+	productIDs := make([]int, 3)
+	for i := range productIDs {
+		err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ($1, $2, $3) RETURNING id",
+			fmt.Sprintf("Produk Stress %d", i+1), 1000*(i+1), initialStock).Scan(&productIDs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		sold     = make(map[int]int)
+		success  int
+		rejected int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				// Urutan produk diputar per worker supaya lock tanpa pengurutan pasti saling silang;
+				// produk pertama muncul dua kali sebagai baris terpisah
+				first := productIDs[(w+r)%len(productIDs)]
+				items := []models.CheckoutItem{{ProductID: first, Quantity: 1}}
+				for i := 1; i < len(productIDs); i++ {
+					items = append(items, models.CheckoutItem{ProductID: productIDs[(w+r+i)%len(productIDs)], Quantity: 1})
+				}
+				items = append(items, models.CheckoutItem{ProductID: first, Quantity: 1})
+
+				_, err := repo.CreateTransaction(models.NewTransaction{Items: items})
+
+				mu.Lock()
+				switch {
+				case err == nil:
+					success++
+					for _, item := range items {
+						sold[item.ProductID] += item.Quantity
+					}
+				case isDeadlock(err):
+					t.Errorf("checkout worker %d gagal karena deadlock: %v", w, err)
+				case strings.Contains(err.Error(), "tidak cukup"):
+					rejected++
+				default:
+					t.Errorf("checkout worker %d gagal: %v", w, err)
+				}
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if success == 0 {
+		t.Fatal("tidak ada checkout yang berhasil")
+	}
+	if rejected == 0 {
+		t.Error("permintaan melebihi stok tapi tidak ada checkout yang ditolak")
+	}
+
+	for _, id := range productIDs {
+		var stock, ledger int
+		err := db.QueryRow(`SELECT p.stock, COALESCE(SUM(sm.quantity), 0) FROM products p
+			LEFT JOIN stock_movements sm ON sm.product_id = p.id AND sm.type = $2
+			WHERE p.id = $1 GROUP BY p.id`, id, models.StockMovementSale).Scan(&stock, &ledger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stock < 0 {
+			t.Errorf("produk %d: stok negatif (%d)", id, stock)
+		}
+		if sold[id] > initialStock {
+			t.Errorf("produk %d: oversell, terjual %d dari stok %d", id, sold[id], initialStock)
+		}
+		if stock != initialStock-sold[id] {
+			t.Errorf("produk %d: stok %d, seharusnya %d (terjual %d)", id, stock, initialStock-sold[id], sold[id])
+		}
+		if -ledger != sold[id] {
+			t.Errorf("produk %d: ledger penjualan %d, seharusnya -%d", id, ledger, sold[id])
+		}
+	}
+}
+
+func isDeadlock(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40P01"
+}
+
+func TestSettlePayments(t *testing.T) {
+	type payment struct {
+		method                   string
+		amount, tendered, change int
+	}
+	tests := []struct {
+		name       string
+		total      int
+		inputs     []models.PaymentInput
+		want       []payment
+		wantPaid   int
+		wantChange int
+		wantErr    bool
+	}{
+		{
+			name:     "tanpa pembayaran dianggap tunai pas",
+			total:    25000,
+			want:     []payment{{models.PaymentMethodCash, 25000, 25000, 0}},
+			wantPaid: 25000,
+		},
+		{
+			name:       "tunai lebih, ada kembalian",
+			total:      23500,
+			inputs:     []models.PaymentInput{{Method: models.PaymentMethodCash, Amount: 50000}},
+			want:       []payment{{models.PaymentMethodCash, 23500, 50000, 26500}},
+			wantPaid:   50000,
+			wantChange: 26500,
+		},
+		{
+			name:  "non-tunai dulu, sisa tunai dengan kembalian walau tunai disebut lebih dulu",
+			total: 30000,
+			inputs: []models.PaymentInput{
+				{Method: models.PaymentMethodCash, Amount: 20000},
+				{Method: models.PaymentMethodQRIS, Amount: 15000},
+			},
+			want: []payment{
+				{models.PaymentMethodCash, 15000, 20000, 5000},
+				{models.PaymentMethodQRIS, 15000, 15000, 0},
+			},
+			wantPaid:   35000,
+			wantChange: 5000,
+		},
+		{
+			name:  "dua tunai, kembalian dari tunai terakhir",
+			total: 30000,
+			inputs: []models.PaymentInput{
+				{Method: models.PaymentMethodCash, Amount: 20000},
+				{Method: models.PaymentMethodCash, Amount: 20000},
+			},
+			want: []payment{
+				{models.PaymentMethodCash, 20000, 20000, 0},
+				{models.PaymentMethodCash, 10000, 20000, 10000},
+			},
+			wantPaid:   40000,
+			wantChange: 10000,
+		},
+		{
+			name:     "non-tunai pas",
+			total:    30000,
+			inputs:   []models.PaymentInput{{Method: models.PaymentMethodDebit, Amount: 30000}},
+			want:     []payment{{models.PaymentMethodDebit, 30000, 30000, 0}},
+			wantPaid: 30000,
+		},
+		{
+			name:    "non-tunai lebih dari tagihan ditolak",
+			total:   30000,
+			inputs:  []models.PaymentInput{{Method: models.PaymentMethodQRIS, Amount: 30001}},
+			wantErr: true,
+		},
+		{
+			name:  "gabungan non-tunai melebihi tagihan ditolak",
+			total: 30000,
+			inputs: []models.PaymentInput{
+				{Method: models.PaymentMethodDebit, Amount: 20000},
+				{Method: models.PaymentMethodEWallet, Amount: 15000},
+			},
+			wantErr: true,
+		},
+		{
+			name:  "non-tunai lebih tidak bisa ditutup dengan tunai",
+			total: 30000,
+			inputs: []models.PaymentInput{
+				{Method: models.PaymentMethodCash, Amount: 10000},
+				{Method: models.PaymentMethodCredit, Amount: 35000},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "pembayaran kurang",
+			total:   30000,
+			inputs:  []models.PaymentInput{{Method: models.PaymentMethodCash, Amount: 29000}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, paid, change, err := settlePayments(tt.total, tt.inputs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("settlePayments() = %+v, want error", payments)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("settlePayments() error: %v", err)
+			}
+			if paid != tt.wantPaid || change != tt.wantChange {
+				t.Errorf("paid, change = %d, %d, want %d, %d", paid, change, tt.wantPaid, tt.wantChange)
+			}
+			if len(payments) != len(tt.want) {
+				t.Fatalf("got %d payments, want %d", len(payments), len(tt.want))
+			}
+			applied := 0
+			for i, p := range payments {
+				got := payment{p.Method, p.Amount, p.Tendered, p.ChangeAmount}
+				if got != tt.want[i] {
+					t.Errorf("payment %d = %+v, want %+v", i, got, tt.want[i])
+				}
+				applied += p.Amount
+			}
+			if applied != tt.total {
+				t.Errorf("jumlah amount %d, want total %d", applied, tt.total)
+			}
+		})
+	}
+}
+
+// TestCheckoutUsesOnlyPriceOverride - checkout hanya menghabiskan override harga, dan hanya jika ada
+// harga manual; token void/refund yang ikut terkirim tidak membuat checkout gagal
+func TestCheckoutUsesOnlyPriceOverride(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	repo := NewTransactionRepository(db)
+
+	ids := make(map[string]int)
+	for _, u := range []struct{ username, role string }{{"spv", models.RoleSupervisor}, {"kasir", models.RoleCashier}} {
+		user := &models.User{Username: u.username, PasswordHash: "-", Role: u.role, IsActive: true}
+		if err := users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		ids[u.username] = user.ID
+	}
+	var productID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Produk Override', 10000, 10) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	cashier := models.Actor{UserID: ids["kasir"]}
+	plain := []models.CheckoutItem{{ProductID: productID, Quantity: 1}}
+	manualPrice := 8000
+	manual := []models.CheckoutItem{{ProductID: productID, Quantity: 1, UnitPrice: &manualPrice}}
+
+	first, err := repo.CreateTransaction(models.NewTransaction{Items: plain, Actor: cashier})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withOverride := func(id string, perm models.Permission, transactionID int) models.Actor {
+		t.Helper()
+		err := users.CreateOverride(&models.SupervisorOverride{
+			ID: id, ApproverID: ids["spv"], Permission: perm, RequestedBy: ids["kasir"], TransactionID: transactionID,
+		}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actor := cashier
+		actor.ApprovedBy, actor.OverrideID, actor.OverridePermission = ids["spv"], id, perm
+		return actor
+	}
+	stillActive := func(id string, want bool) {
+		t.Helper()
+		active, err := users.GetActiveOverride(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (active != nil) != want {
+			t.Errorf("override %s aktif = %v, want %v", id, active != nil, want)
+		}
+	}
+
+	voidActor := withOverride("override-void", models.PermVoid, first.ID)
+	trx, err := repo.CreateTransaction(models.NewTransaction{Items: plain, Actor: voidActor})
+	if err != nil {
+		t.Fatalf("checkout dengan token void: %v", err)
+	}
+	if trx.ApprovedBy != 0 {
+		t.Errorf("approved_by = %d, want 0 (token void tidak dipakai)", trx.ApprovedBy)
+	}
+	stillActive("override-void", true)
+
+	priceActor := withOverride("override-harga", models.PermPriceOverride, 0)
+	if _, err := repo.CreateTransaction(models.NewTransaction{Items: plain, Actor: priceActor}); err != nil {
+		t.Fatal(err)
+	}
+	stillActive("override-harga", true)
+
+	trx, err = repo.CreateTransaction(models.NewTransaction{Items: manual, Actor: priceActor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trx.ApprovedBy != ids["spv"] {
+		t.Errorf("approved_by = %d, want supervisor %d", trx.ApprovedBy, ids["spv"])
+	}
+	stillActive("override-harga", false)
+}
+
+// TestRefundShiftAttribution - refund tunai oleh user tanpa shift masuk ke shift transaksi asal jika
+// masih terbuka; jika tidak ada shift sama sekali ditolak, kecuali refund tanpa porsi tunai
+func TestRefundShiftAttribution(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	shifts := NewShiftRepository(db)
+	repo := NewTransactionRepository(db)
+
+	ids := make(map[string]int)
+	for _, u := range []struct{ username, role string }{{"spv", models.RoleSupervisor}, {"kasir", models.RoleCashier}} {
+		user := &models.User{Username: u.username, PasswordHash: "-", Role: u.role, IsActive: true}
+		if err := users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		ids[u.username] = user.ID
+	}
+	var productID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Produk Shift', 10000, 10) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+	cashier := models.Actor{UserID: ids["kasir"]}
+	supervisor := models.Actor{UserID: ids["spv"]}
+	checkout := func(payments []models.PaymentInput) *models.Transaction {
+		t.Helper()
+		trx, err := repo.CreateTransaction(models.NewTransaction{
+			Items:    []models.CheckoutItem{{ProductID: productID, Quantity: 1}},
+			Payments: payments,
+			Actor:    cashier,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trx
+	}
+
+	shift, err := shifts.Open(ids["kasir"], "", 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sale := checkout(nil)
+	refund, err := repo.CreateRefund(sale.ID, models.RefundTypeVoid, "salah input", nil, supervisor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.ShiftID != shift.ID {
+		t.Errorf("refund shift_id = %d, want shift transaksi asal %d", refund.ShiftID, shift.ID)
+	}
+	cash, err := getShiftCashSummary(db, shift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cash.RefundTunai != 10000 || cash.KasDiharapkan != 50000 {
+		t.Errorf("kas shift = %+v, want refund tunai 10000 dan kas diharapkan 50000", cash)
+	}
+	if _, err := shifts.Close(ids["kasir"], 50000, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shift transaksi asal sudah ditutup dan supervisor tidak punya shift
+	cashSale := checkout(nil)
+	_, err = repo.CreateRefund(cashSale.ID, models.RefundTypeVoid, "salah input", nil, supervisor)
+	if !errors.Is(err, ErrInvalidRefund) || !errors.Is(err, ErrNoOpenShift) {
+		t.Errorf("refund tunai tanpa shift: err = %v, want ErrInvalidRefund dan ErrNoOpenShift", err)
+	}
+
+	qrisSale := checkout([]models.PaymentInput{{Method: models.PaymentMethodQRIS, Amount: 10000}})
+	refund, err = repo.CreateRefund(qrisSale.ID, models.RefundTypeVoid, "salah input", nil, supervisor)
+	if err != nil {
+		t.Fatalf("refund non-tunai tanpa shift: %v", err)
+	}
+	if refund.ShiftID != 0 {
+		t.Errorf("refund non-tunai shift_id = %d, want 0", refund.ShiftID)
+	}
+}
+
+// TestIdempotencyKeyPerCashier - Idempotency-Key yang sama dari dua kasir menghasilkan dua transaksi
+// terpisah; hanya kasir pemilik key yang bisa membaca record-nya atau bentrok dengan key itu lagi
+func TestIdempotencyKeyPerCashier(t *testing.T) {
+	db := openTestDB(t)
+	users := NewUserRepository(db)
+	repo := NewTransactionRepository(db)
+
+	var cashiers []int
+	for _, username := range []string{"kasir1", "kasir2"} {
+		user := &models.User{Username: username, PasswordHash: "-", Role: models.RoleCashier, IsActive: true}
+		if err := users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		cashiers = append(cashiers, user.ID)
+	}
+	var productID int
+	if err := db.QueryRow("INSERT INTO products (name, price, stock) VALUES ('Produk Idempotency', 10000, 10) RETURNING id").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	checkout := func(cashierID int) (*models.Transaction, error) {
+		return repo.CreateTransaction(models.NewTransaction{
+			Items:       []models.CheckoutItem{{ProductID: productID, Quantity: 1}},
+			Actor:       models.Actor{UserID: cashierID},
+			Idempotency: &models.IdempotencyRecord{Key: "key-sama", CashierID: cashierID, RequestHash: "hash-sama"},
+		})
+	}
+
+	since := time.Now().Add(-time.Hour)
+	transactionIDs := make(map[int]int)
+	for _, cashierID := range cashiers {
+		trx, err := checkout(cashierID)
+		if err != nil {
+			t.Fatalf("checkout kasir %d: %v", cashierID, err)
+		}
+		transactionIDs[cashierID] = trx.ID
+
+		rec, err := repo.GetIdempotencyRecord(cashierID, "key-sama", since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec == nil || rec.TransactionID != trx.ID {
+			t.Errorf("record kasir %d = %+v, want transaksi %d", cashierID, rec, trx.ID)
+		}
+	}
+	if transactionIDs[cashiers[0]] == transactionIDs[cashiers[1]] {
+		t.Errorf("dua kasir mendapat transaksi yang sama (%d)", transactionIDs[cashiers[0]])
+	}
+
+	if _, err := checkout(cashiers[0]); !errors.Is(err, ErrIdempotencyKeyExists) {
+		t.Errorf("key dipakai ulang kasir yang sama: err = %v, want ErrIdempotencyKeyExists", err)
+	}
+	if rec, err := repo.GetIdempotencyRecord(0, "key-sama", since); err != nil || rec != nil {
+		t.Errorf("record untuk kasir lain = %+v (err = %v), want nil", rec, err)
+	}
+}
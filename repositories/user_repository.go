@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"kasir-api/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrOverrideRejected - override supervisor sudah dipakai, kedaluwarsa, atau bukan untuk kasir / aksi / transaksi ini
+var ErrOverrideRejected = errors.New("override supervisor sudah dipakai, kedaluwarsa, atau bukan untuk aksi ini")
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (repo *UserRepository) Count() (int, error) {
+	var count int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+func (repo *UserRepository) Create(user *models.User) error {
+	query := "INSERT INTO users (username, password_hash, name, role, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at"
+	return repo.db.QueryRow(query, user.Username, user.PasswordHash, user.Name, user.Role, user.IsActive).Scan(&user.ID, &user.CreatedAt)
+}
+
+func (repo *UserRepository) GetAll() ([]models.User, error) {
+	rows, err := repo.db.Query("SELECT id, username, password_hash, name, role, is_active, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var u models.User
+		err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Name, &u.Role, &u.IsActive, &u.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (repo *UserRepository) Update(user *models.User) error {
+	query := "UPDATE users SET name = $1, role = $2, is_active = $3, password_hash = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $5"
+	result, err := repo.db.Exec(query, user.Name, user.Role, user.IsActive, user.PasswordHash, user.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errors.New("user tidak ditemukan")
+	}
+
+	return nil
+}
+
+// RevokeUserSessions - akhiri semua sesi user, dipakai saat user dinonaktifkan atau ganti password
+func (repo *UserRepository) RevokeUserSessions(userID int) error {
+	_, err := repo.db.Exec("UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+func (repo *UserRepository) GetByUsername(username string) (*models.User, error) {
+	query := "SELECT id, username, password_hash, name, role, is_active, created_at FROM users WHERE username = $1"
+
+	var u models.User
+	err := repo.db.QueryRow(query, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Name, &u.Role, &u.IsActive, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("user tidak ditemukan")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (repo *UserRepository) GetByID(id int) (*models.User, error) {
+	query := "SELECT id, username, password_hash, name, role, is_active, created_at FROM users WHERE id = $1"
+
+	var u models.User
+	err := repo.db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Name, &u.Role, &u.IsActive, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("user tidak ditemukan")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (repo *UserRepository) CreateSession(userID int, refreshTokenHash string, expiresAt time.Time) (int, error) {
+	var id int
+	err := repo.db.QueryRow(
+		"INSERT INTO user_sessions (user_id, refresh_token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id",
+		userID, refreshTokenHash, expiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+func (repo *UserRepository) GetSessionByRefreshHash(refreshTokenHash string) (*models.UserSession, error) {
+	var s models.UserSession
+	err := repo.db.QueryRow(
+		"SELECT id, user_id, expires_at, revoked_at FROM user_sessions WHERE refresh_token_hash = $1",
+		refreshTokenHash,
+	).Scan(&s.ID, &s.UserID, &s.ExpiresAt, &s.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("sesi tidak ditemukan")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetActiveSession - user pemilik sesi dengan role terkini; nil jika sesi sudah di-revoke,
+// kedaluwarsa, atau user dinonaktifkan
+func (repo *UserRepository) GetActiveSession(sessionID int) (*models.AuthUser, error) {
+	var u models.AuthUser
+	err := repo.db.QueryRow(`SELECT u.id, u.username, u.role, s.id
+		FROM user_sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.id = $1 AND s.revoked_at IS NULL AND s.expires_at > CURRENT_TIMESTAMP AND u.is_active`,
+		sessionID).Scan(&u.UserID, &u.Username, &u.Role, &u.SessionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// RevokeSession - revoke sesi; false jika sesi sudah di-revoke sebelumnya
+func (repo *UserRepository) RevokeSession(sessionID int) (bool, error) {
+	result, err := repo.db.Exec(
+		"UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL",
+		sessionID,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// CreateOverride - simpan override baru yang berlaku ttl sejak sekarang (jam database). Override lama yang
+// kedaluwarsa tanpa pernah dipakai ikut dibersihkan; yang sudah dipakai disimpan sebagai jejak audit.
+func (repo *UserRepository) CreateOverride(o *models.SupervisorOverride, ttl time.Duration) error {
+	_, err := repo.db.Exec("DELETE FROM supervisor_overrides WHERE used_at IS NULL AND expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.db.Exec(
+		`INSERT INTO supervisor_overrides (jti, permission, approver_id, requested_by, transaction_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + make_interval(secs => $6))`,
+		o.ID, o.Permission, o.ApproverID, o.RequestedBy, nullableID(o.TransactionID), ttl.Seconds(),
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "supervisor_overrides_transaction_id_fkey" {
+		return ErrTransactionNotFound
+	}
+	return err
+}
+
+// GetActiveOverride - override yang belum dipakai dan belum kedaluwarsa; nil jika tidak ada
+func (repo *UserRepository) GetActiveOverride(jti string) (*models.SupervisorOverride, error) {
+	var o models.SupervisorOverride
+	var permission string
+	var transactionID sql.NullInt64
+	err := repo.db.QueryRow(
+		`SELECT jti, permission, approver_id, requested_by, transaction_id FROM supervisor_overrides
+		WHERE jti = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`,
+		jti,
+	).Scan(&o.ID, &permission, &o.ApproverID, &o.RequestedBy, &transactionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.Permission = models.Permission(permission)
+	o.TransactionID = int(transactionID.Int64)
+	return &o, nil
+}
+
+// consumeOverride - tandai override milik actor terpakai untuk izin perm pada transaksi transactionID
+// (0 untuk checkout) di dalam DB transaction aksi tersebut; no-op jika actor tidak memakai override.
+// Jika aksinya gagal dan di-rollback, override masih bisa dipakai sampai kedaluwarsa.
+func consumeOverride(tx *sql.Tx, actor models.Actor, perm models.Permission, transactionID int) error {
+	if actor.OverrideID == "" {
+		return nil
+	}
+	result, err := tx.Exec(
+		`UPDATE supervisor_overrides SET used_at = CURRENT_TIMESTAMP
+		WHERE jti = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		  AND permission = $2 AND requested_by = $3 AND approver_id = $4
+		  AND transaction_id IS NOT DISTINCT FROM $5`,
+		actor.OverrideID, perm, actor.UserID, actor.ApprovedBy, nullableID(transactionID),
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOverrideRejected
+	}
+	return nil
+}
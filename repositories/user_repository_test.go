@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"errors"
+	"kasir-api/models"
+	"testing"
+	"time"
+)
+
+// TestConsumeOverride - override supervisor hanya bisa dipakai sekali, oleh kasir yang memintanya,
+// untuk izin dan transaksi yang disetujui; rollback aksi mengembalikan override supaya bisa dipakai lagi
+func TestConsumeOverride(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewUserRepository(db)
+
+	users := make(map[string]int)
+	for _, u := range []struct{ username, role string }{
+		{"spv", models.RoleSupervisor}, {"kasir1", models.RoleCashier}, {"kasir2", models.RoleCashier},
+	} {
+		user := &models.User{Username: u.username, PasswordHash: "-", Role: u.role, IsActive: true}
+		if err := repo.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		users[u.username] = user.ID
+	}
+
+	override := &models.SupervisorOverride{
+		ID:          "override-harga",
+		ApproverID:  users["spv"],
+		Permission:  models.PermPriceOverride,
+		RequestedBy: users["kasir1"],
+	}
+	if err := repo.CreateOverride(override, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateOverride(&models.SupervisorOverride{
+		ID: "override-void", ApproverID: users["spv"], Permission: models.PermVoid,
+		RequestedBy: users["kasir1"], TransactionID: 999999,
+	}, time.Minute); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("override untuk transaksi yang tidak ada: err = %v, want ErrTransactionNotFound", err)
+	}
+
+	actor := models.Actor{UserID: users["kasir1"], ApprovedBy: users["spv"], OverrideID: override.ID}
+	consume := func(actor models.Actor, perm models.Permission, transactionID int, commit bool) error {
+		t.Helper()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err := consumeOverride(tx, actor, perm, transactionID); err != nil {
+			return err
+		}
+		if commit {
+			return tx.Commit()
+		}
+		return nil
+	}
+
+	otherCashier := actor
+	otherCashier.UserID = users["kasir2"]
+	rejected := []struct {
+		name          string
+		actor         models.Actor
+		perm          models.Permission
+		transactionID int
+	}{
+		{"kasir lain", otherCashier, models.PermPriceOverride, 0},
+		{"izin lain", actor, models.PermVoid, 0},
+		{"terikat transaksi lain", actor, models.PermPriceOverride, 1},
+	}
+	for _, tt := range rejected {
+		if err := consume(tt.actor, tt.perm, tt.transactionID, true); !errors.Is(err, ErrOverrideRejected) {
+			t.Errorf("%s: err = %v, want ErrOverrideRejected", tt.name, err)
+		}
+	}
+
+	if err := consume(actor, models.PermPriceOverride, 0, false); err != nil {
+		t.Fatalf("pemakaian yang di-rollback: %v", err)
+	}
+	if active, err := repo.GetActiveOverride(override.ID); err != nil || active == nil {
+		t.Fatalf("override harus masih aktif setelah rollback (active = %v, err = %v)", active, err)
+	}
+	if err := consume(actor, models.PermPriceOverride, 0, true); err != nil {
+		t.Fatalf("pemakaian pertama: %v", err)
+	}
+	if err := consume(actor, models.PermPriceOverride, 0, true); !errors.Is(err, ErrOverrideRejected) {
+		t.Errorf("pemakaian kedua: err = %v, want ErrOverrideRejected", err)
+	}
+	if active, err := repo.GetActiveOverride(override.ID); err != nil || active != nil {
+		t.Errorf("override yang sudah dipakai masih aktif (active = %v, err = %v)", active, err)
+	}
+	if err := consume(models.Actor{UserID: users["kasir1"]}, models.PermPriceOverride, 0, true); err != nil {
+		t.Errorf("tanpa override harus no-op: %v", err)
+	}
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+	overrideTokenTTL       = 2 * time.Minute
+	minPasswordLength      = 8
+
+	// Audience membedakan access token dan override token supaya tidak bisa saling dipakai
+	accessTokenAudience   = "kasir-api"
+	overrideTokenAudience = "kasir-api:override"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("username atau password salah")
+	ErrInvalidToken       = errors.New("token tidak valid atau sesi sudah berakhir")
+	ErrInvalidOverride    = errors.New("override supervisor tidak valid atau sudah kedaluwarsa")
+)
+
+// dummyPasswordHash - dipakai saat username tidak ada, supaya waktu respons login
+// tidak membocorkan username mana yang valid
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("kasir-api"), bcrypt.DefaultCost)
+
+type AuthService struct {
+	repo            *repositories.UserRepository
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewAuthService(repo *repositories.UserRepository, secret string, accessTokenTTL, refreshTokenTTL time.Duration) *AuthService {
+	if accessTokenTTL <= 0 {
+		accessTokenTTL = defaultAccessTokenTTL
+	}
+	if refreshTokenTTL <= 0 {
+		refreshTokenTTL = defaultRefreshTokenTTL
+	}
+	return &AuthService{
+		repo:            repo,
+		secret:          []byte(secret),
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// accessClaims - claim JWT access token; sid menunjuk ke user_sessions supaya logout langsung berlaku
+type accessClaims struct {
+	Username  string `json:"username"`
+	SessionID int    `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// overrideClaims - claim override token; jti (ID) menunjuk ke supervisor_overrides yang menyimpan
+// kasir peminta, izin, dan transaksi target, serta status sekali pakainya
+type overrideClaims struct {
+	Username   string            `json:"username"`
+	Permission models.Permission `json:"perm"`
+	jwt.RegisteredClaims
+}
+
+// EnsureInitialUser - buat user pertama jika tabel users masih kosong, supaya ada yang bisa login
+func (s *AuthService) EnsureInitialUser(username, password string) error {
+	count, err := s.repo.Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if username == "" || password == "" {
+		return errors.New("belum ada user; set ADMIN_USERNAME dan ADMIN_PASSWORD untuk membuat user pertama")
+	}
+
+	_, err = s.CreateUser(models.CreateUserRequest{
+		Username: username,
+		Password: password,
+		Name:     username,
+		Role:     models.RoleOwner,
+	})
+	return err
+}
+
+func (s *AuthService) GetAllUsers() ([]models.User, error) {
+	return s.repo.GetAll()
+}
+
+func (s *AuthService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return nil, errors.New("username wajib diisi")
+	}
+	if req.Role == "" {
+		req.Role = models.RoleCashier
+	}
+	if err := validateRole(req.Role); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		Name:         req.Name,
+		Role:         req.Role,
+		PasswordHash: hash,
+		IsActive:     true,
+	}
+	if err := s.repo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser - ubah nama, role, status aktif, atau password. Sesi user diakhiri jika
+// user dinonaktifkan atau password diganti.
+func (s *AuthService) UpdateUser(id int, req models.UpdateUserRequest) (*models.User, error) {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	revokeSessions := false
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Role != nil {
+		if err := validateRole(*req.Role); err != nil {
+			return nil, err
+		}
+		user.Role = *req.Role
+	}
+	if req.IsActive != nil {
+		user.IsActive = *req.IsActive
+		revokeSessions = revokeSessions || !user.IsActive
+	}
+	if req.Password != nil {
+		hash, err := hashPassword(*req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+		revokeSessions = true
+	}
+
+	if err := s.repo.Update(user); err != nil {
+		return nil, err
+	}
+	if revokeSessions {
+		if err := s.repo.RevokeUserSessions(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// IssueOverride - verifikasi kredensial supervisor di terminal kasir dan terbitkan token berumur pendek
+// untuk satu aksi kasir requesterID. Kasir mengirimnya lewat header X-Supervisor-Token; token hanya
+// bisa dipakai sekali, oleh kasir tersebut, dan untuk void/refund hanya pada transaksi yang disebut.
+func (s *AuthService) IssueOverride(req models.OverrideRequest, requesterID int) (*models.OverrideResponse, error) {
+	if !slices.Contains(models.OverridablePermissions, req.Permission) {
+		names := make([]string, len(models.OverridablePermissions))
+		for i, perm := range models.OverridablePermissions {
+			names[i] = string(perm)
+		}
+		return nil, fmt.Errorf("permission %q tidak bisa di-override (pilihan: %s)", req.Permission, strings.Join(names, ", "))
+	}
+	if req.Permission == models.PermPriceOverride {
+		if req.TransactionID != 0 {
+			return nil, errors.New("transaction_id tidak dipakai untuk override harga")
+		}
+	} else if req.TransactionID <= 0 {
+		return nil, fmt.Errorf("transaction_id wajib diisi untuk override %s", req.Permission)
+	}
+
+	approver, err := s.checkCredentials(req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !models.HasPermission(approver.Role, req.Permission) {
+		return nil, ErrInvalidCredentials
+	}
+
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	override := &models.SupervisorOverride{
+		ID:            jti,
+		ApproverID:    approver.ID,
+		Permission:    req.Permission,
+		RequestedBy:   requesterID,
+		TransactionID: req.TransactionID,
+	}
+	if err := s.repo.CreateOverride(override, overrideTokenTTL); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := overrideClaims{
+		Username:   approver.Username,
+		Permission: req.Permission,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(approver.ID),
+			Audience:  jwt.ClaimStrings{overrideTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(overrideTokenTTL)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OverrideResponse{
+		OverrideToken: token,
+		Permission:    req.Permission,
+		TransactionID: req.TransactionID,
+		ExpiresIn:     int(overrideTokenTTL.Seconds()),
+		Approver:      approver,
+	}, nil
+}
+
+// VerifyOverride - validasi override token untuk user userID: belum dipakai, belum kedaluwarsa, diminta
+// oleh user yang sama, dan approver masih aktif serta masih punya izinnya. Token ditandai terpakai oleh
+// repository di DB transaction aksi yang disetujui (lihat Actor.OverrideID).
+func (s *AuthService) VerifyOverride(tokenString string, userID int) (*models.SupervisorOverride, error) {
+	claims := &overrideClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(overrideTokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid || claims.ID == "" {
+		return nil, ErrInvalidOverride
+	}
+
+	override, err := s.repo.GetActiveOverride(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if override == nil || override.RequestedBy != userID || strconv.Itoa(override.ApproverID) != claims.Subject {
+		return nil, ErrInvalidOverride
+	}
+	approver, err := s.repo.GetByID(override.ApproverID)
+	if err != nil || !approver.IsActive || !models.HasPermission(approver.Role, override.Permission) {
+		return nil, ErrInvalidOverride
+	}
+
+	override.ApproverUsername = approver.Username
+	return override, nil
+}
+
+func (s *AuthService) Login(req models.LoginRequest) (*models.TokenResponse, error) {
+	user, err := s.checkCredentials(req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user)
+}
+
+func (s *AuthService) checkCredentials(username, password string) (*models.User, error) {
+	user, err := s.repo.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Refresh - tukar refresh token dengan pasangan token baru; refresh token lama langsung di-revoke (rotasi)
+func (s *AuthService) Refresh(refreshToken string) (*models.TokenResponse, error) {
+	session, err := s.repo.GetSessionByRefreshHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.repo.RevokeSession(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// Refresh token yang sama dipakai dua kali bersamaan
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.GetByID(session.UserID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidToken
+	}
+
+	return s.issueTokens(user)
+}
+
+func (s *AuthService) Logout(user *models.AuthUser) error {
+	_, err := s.repo.RevokeSession(user.SessionID)
+	return err
+}
+
+// Authenticate - validasi access token dan pastikan sesinya masih aktif
+func (s *AuthService) Authenticate(tokenString string) (*models.AuthUser, error) {
+	claims := &accessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(accessTokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// Role diambil dari database, bukan dari token, supaya perubahan role langsung berlaku
+	user, err := s.repo.GetActiveSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || strconv.Itoa(user.UserID) != claims.Subject {
+		return nil, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) keyFunc(*jwt.Token) (interface{}, error) {
+	return s.secret, nil
+}
+
+func (s *AuthService) GetUser(id int) (*models.User, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *AuthService) issueTokens(user *models.User) (*models.TokenResponse, error) {
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessionID, err := s.repo.CreateSession(user.ID, hashToken(refreshToken), now.Add(s.refreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := accessClaims{
+		Username:  user.Username,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(user.ID),
+			Audience:  jwt.ClaimStrings{accessTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTokenTTL)),
+		},
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(models.Roles, role) {
+		return fmt.Errorf("role %q tidak dikenal (pilihan: %s)", role, strings.Join(models.Roles, ", "))
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password minimal %d karakter", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
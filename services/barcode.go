@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxSKULen = 64
+
+// normalizeBarcode - validasi check digit EAN-8, EAN-13 atau UPC-A dan kembalikan bentuk yang disimpan.
+// UPC-A (12 digit) dijadikan EAN-13 dengan awalan 0 supaya hasil scan keduanya menemukan produk yang sama.
+func normalizeBarcode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("barcode %q hanya boleh berisi angka", code)
+		}
+	}
+
+	switch len(code) {
+	case 8, 13:
+	case 12:
+		code = "0" + code
+	default:
+		return "", fmt.Errorf("barcode %q harus 8 (EAN-8), 12 (UPC-A) atau 13 (EAN-13) digit", code)
+	}
+
+	if !validGTINCheckDigit(code) {
+		return "", fmt.Errorf("check digit barcode %q tidak valid", code)
+	}
+	return code, nil
+}
+
+// validGTINCheckDigit - digit terakhir = (10 - (jumlah digit berbobot 3,1,3,... dari kanan) mod 10) mod 10
+func validGTINCheckDigit(code string) bool {
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
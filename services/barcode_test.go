@@ -0,0 +1,43 @@
+package services
+
+import "testing"
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{name: "ean-13", code: "4006381333931", want: "4006381333931"},
+		{name: "ean-8", code: "96385074", want: "96385074"},
+		{name: "upc-a dijadikan ean-13", code: "036000291452", want: "0036000291452"},
+		{name: "upc-a dan ean-13-nya sama", code: "0036000291452", want: "0036000291452"},
+		{name: "spasi dan newline dari scanner dibuang", code: " 4006381333931\r\n", want: "4006381333931"},
+		{name: "tab di depan", code: "\t036000291452", want: "0036000291452"},
+		{name: "check digit ean-13 salah", code: "4006381333932", wantErr: true},
+		{name: "check digit upc-a salah", code: "036000291453", wantErr: true},
+		{name: "check digit ean-8 salah", code: "96385075", wantErr: true},
+		{name: "spasi di tengah", code: "4006381 333931", wantErr: true},
+		{name: "huruf", code: "40063813339A1", wantErr: true},
+		{name: "panjang tidak dikenal", code: "1234567890", wantErr: true},
+		{name: "kosong", code: "   ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeBarcode(tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizeBarcode(%q) = %q, want error", tt.code, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeBarcode(%q) error: %v", tt.code, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeBarcode(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"errors"
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"strings"
 )
 
 type CategoryService struct {
@@ -13,11 +15,15 @@ func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService
 	return &CategoryService{repo: repo}
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
+// GetAll - kategori aktif, atau hanya yang diarsipkan jika archived true
+func (s *CategoryService) GetAll(archived bool) ([]models.Category, error) {
+	return s.repo.GetAll(archived)
 }
 
 func (s *CategoryService) Create(data *models.Category) error {
+	if err := validateTaxRate(data.TaxRate); err != nil {
+		return err
+	}
 	return s.repo.Create(data)
 }
 
@@ -26,9 +32,58 @@ func (s *CategoryService) GetByID(id int) (*models.Category, error) {
 }
 
 func (s *CategoryService) Update(category *models.Category) error {
+	if err := validateTaxRate(category.TaxRate); err != nil {
+		return err
+	}
 	return s.repo.Update(category)
 }
 
+// Patch - ubah sebagian field kategori; field yang tidak dikirim tetap. Tanpa version dari client,
+// perubahan dicek terhadap versi yang baru dibaca (dicoba ulang beberapa kali).
+func (s *CategoryService) Patch(id int, patch models.CategoryPatch) (*models.Category, error) {
+	if patch.Name != nil && strings.TrimSpace(*patch.Name) == "" {
+		return nil, errors.New("name tidak boleh kosong")
+	}
+
+	for attempt := 1; ; attempt++ {
+		category, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if patch.Version > 0 && patch.Version != category.Version {
+			return nil, repositories.ErrVersionConflict
+		}
+		if patch.Name != nil {
+			category.Name = *patch.Name
+		}
+		if patch.Description != nil {
+			category.Description = *patch.Description
+		}
+		if patch.TaxRate.Set {
+			category.TaxRate = patch.TaxRate.Value
+		}
+
+		err = s.Update(category)
+		if errors.Is(err, repositories.ErrVersionConflict) && patch.Version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return category, nil
+	}
+}
+
+// Delete - hapus permanen, hanya untuk kategori yang tidak dipakai
 func (s *CategoryService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
+
+// Archive - sembunyikan kategori dari daftar tanpa mengubah produknya
+func (s *CategoryService) Archive(id int) error {
+	return s.repo.Archive(id)
+}
+
+func (s *CategoryService) Restore(id int) error {
+	return s.repo.Restore(id)
+}
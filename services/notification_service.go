@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+
+	webhookBatchSize   = 50
+	webhookMaxAttempts = 10
+	// Notifikasi lebih lama dari ini tidak dikirim lagi, mis. saat webhook baru dikonfigurasi
+	webhookMaxAge = 24 * time.Hour
+)
+
+type NotificationService struct {
+	repo          *repositories.NotificationRepository
+	webhookURL    string
+	webhookSecret string
+	client        *http.Client
+}
+
+// NewNotificationService - webhookURL kosong berarti notifikasi hanya tersedia in-app
+func NewNotificationService(repo *repositories.NotificationRepository, webhookURL, webhookSecret string) *NotificationService {
+	return &NotificationService{
+		repo:          repo,
+		webhookURL:    webhookURL,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *NotificationService) GetAll(filter models.NotificationFilter) ([]models.Notification, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultNotificationLimit
+	}
+	if filter.Limit > maxNotificationLimit {
+		filter.Limit = maxNotificationLimit
+	}
+	return s.repo.GetAll(filter)
+}
+
+func (s *NotificationService) MarkRead(id int64) error {
+	return s.repo.MarkRead(id)
+}
+
+// StartWebhookDispatcher - kirim notifikasi yang belum terkirim ke webhook secara berkala.
+// Tidak melakukan apa-apa jika webhook tidak dikonfigurasi.
+func (s *NotificationService) StartWebhookDispatcher(interval time.Duration) {
+	if s.webhookURL == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.DeliverPending(); err != nil {
+				log.Println("gagal mengirim notifikasi webhook:", err)
+			}
+		}
+	}()
+}
+
+// DeliverPending - kirim satu batch notifikasi yang belum terkirim; yang gagal dicoba lagi
+// pada putaran berikutnya sampai webhookMaxAttempts
+func (s *NotificationService) DeliverPending() error {
+	notifications, err := s.repo.GetUndelivered(time.Now().Add(-webhookMaxAge), webhookMaxAttempts, webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		if err := s.sendWebhook(n); err != nil {
+			if err := s.repo.MarkDeliveryFailed(n.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.repo.MarkDelivered(n.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendWebhook - POST JSON ke webhook; jika secret diisi, body ditandatangani HMAC-SHA256
+// di header X-Kasir-Signature (hex)
+func (s *NotificationService) sendWebhook(n models.Notification) error {
+	body, err := json.Marshal(models.WebhookEvent{
+		ID:        n.ID,
+		Event:     n.Type,
+		Message:   n.Message,
+		Data:      n.Payload,
+		CreatedAt: n.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Kasir-Event", n.Type)
+	if s.webhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-Kasir-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook membalas status %d", resp.StatusCode)
+	}
+	return nil
+}
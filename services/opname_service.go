@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type OpnameService struct {
+	repo *repositories.OpnameRepository
+}
+
+func NewOpnameService(repo *repositories.OpnameRepository) *OpnameService {
+	return &OpnameService{repo: repo}
+}
+
+func (s *OpnameService) Create(req models.CreateOpnameRequest, userID int) (*models.OpnameSession, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("name wajib diisi")
+	}
+	session := &models.OpnameSession{
+		Name:      req.Name,
+		Note:      req.Note,
+		CreatedBy: userID,
+	}
+	if err := s.repo.Create(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *OpnameService) GetAll() ([]models.OpnameSession, error) {
+	return s.repo.GetAll()
+}
+
+func (s *OpnameService) GetByID(id int) (*models.OpnameSession, error) {
+	return s.repo.GetByID(id)
+}
+
+// SubmitCounts - terima hasil hitung dari satu perangkat (X-Terminal-ID)
+func (s *OpnameService) SubmitCounts(sessionID int, req models.OpnameCountRequest, actor models.Actor) ([]models.OpnameCount, error) {
+	if len(req.Items) == 0 {
+		return nil, errors.New("items tidak boleh kosong")
+	}
+	if len(actor.TerminalID) > maxTerminalIDLen {
+		return nil, fmt.Errorf("X-Terminal-ID maksimal %d karakter", maxTerminalIDLen)
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.CountedQty < 0 {
+			return nil, fmt.Errorf("counted_qty untuk product id %d tidak boleh negatif", item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("product id %d muncul lebih dari sekali", item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+
+	return s.repo.SubmitCounts(sessionID, actor.TerminalID, actor.UserID, req.Items)
+}
+
+func (s *OpnameService) GetVarianceReport(id int) (*models.OpnameVarianceReport, error) {
+	session, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.GetVariance(session)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.OpnameVarianceReport{Session: session, Items: items}
+	for _, item := range items {
+		if item.NilaiSelisih < 0 {
+			report.NilaiKurang -= item.NilaiSelisih
+		} else {
+			report.NilaiLebih += item.NilaiSelisih
+		}
+	}
+	report.NilaiBersih = report.NilaiLebih - report.NilaiKurang
+	return report, nil
+}
+
+// Finalize - sesuaikan stok ke hasil hitung dan kembalikan laporan selisih final
+func (s *OpnameService) Finalize(id int, req models.FinalizeOpnameRequest, userID int) (*models.OpnameVarianceReport, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("reason wajib diisi")
+	}
+	if err := s.repo.Finalize(id, userID, req.Reason); err != nil {
+		return nil, err
+	}
+	return s.GetVarianceReport(id)
+}
+
+func (s *OpnameService) Cancel(id int) error {
+	return s.repo.Cancel(id)
+}
@@ -0,0 +1,513 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"kasir-api/models"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	maxImportRows     = 5000
+	maxProductNameLen = 255
+)
+
+// importColumnAliases - nama header yang diterima untuk tiap kolom (huruf kecil, spasi jadi _)
+var importColumnAliases = map[string]string{
+	"id":           "id",
+	"sku":          "sku",
+	"kode":         "sku",
+	"name":         "name",
+	"nama":         "name",
+	"nama_produk":  "name",
+	"category_id":  "category_id",
+	"kategori_id":  "category_id",
+	"category":     "category",
+	"kategori":     "category",
+	"price":        "price",
+	"harga":        "price",
+	"harga_jual":   "price",
+	"cost_price":   "cost_price",
+	"hpp":          "cost_price",
+	"harga_beli":   "cost_price",
+	"stock":        "stock",
+	"stok":         "stock",
+	"min_stock":    "min_stock",
+	"stok_minimum": "min_stock",
+}
+
+// ProductFileFormat - tentukan format dari parameter format atau ekstensi nama file
+func ProductFileFormat(format, filename string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		lower := strings.ToLower(filename)
+		switch {
+		case strings.HasSuffix(lower, ".csv"):
+			format = models.ProductFileCSV
+		case strings.HasSuffix(lower, ".xlsx"):
+			format = models.ProductFileXLSX
+		}
+	}
+	if format != models.ProductFileCSV && format != models.ProductFileXLSX {
+		return "", errors.New("format harus csv atau xlsx")
+	}
+	return format, nil
+}
+
+// Import - validasi file produk lalu simpan semuanya dalam satu DB transaction. Baris dicocokkan ke
+// produk lama by id (kolom pertama hasil export), atau by SKU jika id kosong (upsert); baris tanpa
+// id dan tanpa SKU yang sudah terdaftar dibuat sebagai produk baru.
+// Jika ada satu saja baris yang error, tidak ada yang disimpan; dry-run hanya mengembalikan laporan.
+func (s *ProductService) Import(r io.Reader, format string, opts models.ProductImportOptions, userID int) (*models.ProductImportResult, error) {
+	records, err := readProductFile(r, format)
+	if err != nil {
+		return nil, err
+	}
+	rows, parseErrors, err := parseImportRows(records)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("maksimal %d baris per import", maxImportRows)
+	}
+
+	result := &models.ProductImportResult{
+		DryRun:            opts.DryRun,
+		TotalRows:         len(rows),
+		CategoriesCreated: make([]string, 0),
+		Items:             make([]models.ProductImportItem, 0, len(rows)),
+		Errors:            parseErrors,
+	}
+
+	// Baris yang selnya gagal diparse tidak divalidasi lebih lanjut
+	failed := make(map[int]bool, len(parseErrors))
+	for _, e := range parseErrors {
+		failed[e.Row] = true
+	}
+	valid := slices.DeleteFunc(slices.Clone(rows), func(row models.ProductImportRow) bool { return failed[row.Row] })
+
+	changes, err := s.planImport(valid, opts, result)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(result.Errors, func(a, b models.ProductImportError) int { return a.Row - b.Row })
+	if len(result.Errors) > 0 || opts.DryRun {
+		return result, nil
+	}
+
+	if err := s.repo.Import(changes, result.CategoriesCreated, userID); err != nil {
+		return nil, err
+	}
+	for i, change := range changes {
+		result.Items[i].ProductID = change.Product.ID
+	}
+	return result, nil
+}
+
+// planImport - validasi setiap baris terhadap katalog dan kategori saat ini. Error per baris dicatat
+// di result; error yang dikembalikan hanya error database.
+func (s *ProductService) planImport(rows []models.ProductImportRow, opts models.ProductImportOptions,
+	result *models.ProductImportResult) ([]models.ProductImportChange, error) {
+	skus := make([]string, 0, len(rows))
+	rowIDs := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		if row.SKU != "" {
+			skus = append(skus, row.SKU)
+		}
+		if row.ID > 0 {
+			rowIDs = append(rowIDs, int64(row.ID))
+		}
+	}
+	existing, err := s.repo.GetBySKUs(skus)
+	if err != nil {
+		return nil, err
+	}
+	existingByID, err := s.repo.GetByIDs(rowIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(existing)+len(existingByID))
+	for _, p := range existing {
+		ids = append(ids, int64(p.ID))
+	}
+	for id := range existingByID {
+		ids = append(ids, int64(id))
+	}
+	parents, err := s.repo.GetParentIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make(map[int]bool, len(categories))
+	categoryByName := make(map[string]int, len(categories))
+	for _, c := range categories {
+		categoryIDs[c.ID] = true
+		categoryByName[strings.ToLower(strings.TrimSpace(c.Name))] = c.ID
+	}
+	newCategories := make(map[string]string)
+
+	changes := make([]models.ProductImportChange, 0, len(rows))
+	seenSKU := make(map[string]int)
+	seenID := make(map[int]int)
+	for _, row := range rows {
+		rowErrors := len(result.Errors)
+		addError := func(column, message string) {
+			result.Errors = append(result.Errors, models.ProductImportError{Row: row.Row, Column: column, Message: message})
+		}
+
+		if row.ID != 0 {
+			if first, ok := seenID[row.ID]; ok {
+				addError("id", fmt.Sprintf("id sama dengan baris %d", first))
+			}
+			seenID[row.ID] = row.Row
+		}
+		if row.SKU != "" {
+			if len(row.SKU) > maxSKULen {
+				addError("sku", fmt.Sprintf("sku maksimal %d karakter", maxSKULen))
+			}
+			if first, ok := seenSKU[row.SKU]; ok {
+				addError("sku", fmt.Sprintf("sku sama dengan baris %d", first))
+			}
+			seenSKU[row.SKU] = row.Row
+		}
+		if len([]rune(row.Name)) > maxProductNameLen {
+			addError("name", fmt.Sprintf("name maksimal %d karakter", maxProductNameLen))
+		}
+		for _, column := range []string{"price", "cost_price", "stock", "min_stock"} {
+			if value := *importIntField(&row, column); value != nil && *value < 0 {
+				addError(column, column+" tidak boleh negatif")
+			}
+		}
+
+		categoryID, newCategory := 0, ""
+		switch {
+		case row.CategoryID > 0:
+			if !categoryIDs[row.CategoryID] {
+				addError("category_id", "category_id tidak ditemukan")
+			}
+			categoryID = row.CategoryID
+		case row.CategoryName != "":
+			key := strings.ToLower(row.CategoryName)
+			if id, ok := categoryByName[key]; ok {
+				categoryID = id
+			} else if opts.CreateCategories {
+				if _, ok := newCategories[key]; !ok {
+					newCategories[key] = row.CategoryName
+					result.CategoriesCreated = append(result.CategoriesCreated, row.CategoryName)
+				}
+				newCategory = newCategories[key]
+			} else {
+				addError("category", fmt.Sprintf("kategori %q tidak ditemukan (aktifkan create_categories untuk membuatnya)", row.CategoryName))
+			}
+		}
+		hasCategory := row.CategoryID > 0 || row.CategoryName != ""
+
+		// id menang atas SKU; SKU yang dipakai produk lain ditolak supaya tidak bentrok saat disimpan
+		current := existing[row.SKU]
+		if row.ID != 0 {
+			current = existingByID[row.ID]
+			if current == nil {
+				addError("id", fmt.Sprintf("produk id %d tidak ditemukan", row.ID))
+			} else if other := existing[row.SKU]; other != nil && other.ID != current.ID {
+				addError("sku", fmt.Sprintf("sku sudah dipakai produk id %d", other.ID))
+			}
+		}
+		var product models.Product
+		change := models.ProductImportChange{NewCategory: newCategory}
+		if current == nil {
+			if row.ID != 0 {
+				continue
+			}
+			if row.Name == "" {
+				addError("name", "name wajib diisi untuk produk baru")
+			}
+			if row.Price == nil {
+				addError("price", "price wajib diisi untuk produk baru")
+			}
+			product = models.Product{Name: row.Name, SKU: row.SKU, CategoryID: categoryID, Barcodes: []string{}}
+		} else {
+			product = *current
+			if product.DeletedAt != nil {
+				addError("sku", "produk ini sudah diarsipkan, pulihkan dulu")
+			}
+			if row.SKU != "" {
+				product.SKU = row.SKU
+			}
+			if product.ParentID != 0 {
+				// Nama dan kategori varian mengikuti induk; nilai hasil export boleh dikirim ulang apa adanya
+				if row.Name != "" && row.Name != product.Name {
+					addError("name", "nama varian mengikuti produk induk, kosongkan kolom ini")
+				}
+				if hasCategory && (newCategory != "" || categoryID != product.CategoryID) {
+					addError("category", "kategori varian mengikuti produk induk, kosongkan kolom ini")
+				}
+			} else {
+				if row.Name != "" {
+					product.Name = row.Name
+				}
+				if hasCategory {
+					product.CategoryID = categoryID
+				}
+			}
+			if !opts.UpdateStock {
+				// Stok lama dipertahankan; perubahannya lewat ledger stok atau opname
+				row.Stock = nil
+			}
+			if row.Stock != nil && parents[product.ID] && *row.Stock != product.Stock {
+				addError("stock", "stok produk induk ada di variannya, kosongkan kolom ini")
+			}
+			change.SetStock = row.Stock != nil
+		}
+		if row.Price != nil {
+			product.Price = *row.Price
+		}
+		if row.CostPrice != nil {
+			product.CostPrice = *row.CostPrice
+		}
+		if row.Stock != nil {
+			product.Stock = *row.Stock
+		}
+		if row.MinStock != nil {
+			product.MinStock = *row.MinStock
+		}
+
+		if len(result.Errors) > rowErrors {
+			continue
+		}
+
+		item := models.ProductImportItem{Row: row.Row, Action: models.ProductImportCreate, SKU: product.SKU, Name: product.Name}
+		if current != nil {
+			item.Action = models.ProductImportUpdate
+			item.ProductID = product.ID
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		result.Items = append(result.Items, item)
+
+		change.Product = &product
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// readProductFile - baca seluruh sel file CSV / XLSX (sheet pertama) sebagai tabel string
+func readProductFile(r io.Reader, format string) ([][]string, error) {
+	if format == models.ProductFileXLSX {
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, errors.New("file xlsx tidak valid")
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("file xlsx tidak punya sheet")
+		}
+		// RawCellValue supaya angka tidak ikut format tampilan (mis. "15.000")
+		return f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	}
+
+	br := bufio.NewReader(r)
+	// Excel locale Indonesia menyimpan CSV dengan pemisah titik koma
+	firstLine, _ := br.Peek(4096)
+	if line, _, _ := bytes.Cut(firstLine, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		reader := csv.NewReader(br)
+		reader.Comma = ';'
+		return readCSVRecords(reader)
+	}
+	return readCSVRecords(csv.NewReader(br))
+}
+
+func readCSVRecords(reader *csv.Reader) ([][]string, error) {
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("file csv tidak valid: %v", err)
+	}
+	return records, nil
+}
+
+// parseImportRows - petakan header ke kolom yang dikenal lalu parse tiap baris. Nomor baris
+// mengikuti file (header = baris 1); baris kosong dilewati. Sel angka yang tidak valid dilaporkan
+// per baris; error hanya dikembalikan jika header tidak bisa dipakai.
+func parseImportRows(records [][]string) ([]models.ProductImportRow, []models.ProductImportError, error) {
+	if len(records) == 0 {
+		return nil, nil, errors.New("file kosong")
+	}
+
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+		key = strings.ReplaceAll(key, " ", "_")
+		if column, ok := importColumnAliases[key]; ok {
+			if _, dup := columns[column]; dup {
+				return nil, nil, fmt.Errorf("kolom %s muncul lebih dari sekali", column)
+			}
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		if _, ok := columns["sku"]; !ok {
+			return nil, nil, errors.New("header harus punya kolom name atau sku")
+		}
+	}
+
+	rows := make([]models.ProductImportRow, 0, len(records)-1)
+	rowErrors := make([]models.ProductImportError, 0)
+	for i, record := range records[1:] {
+		cell := func(column string) string {
+			idx, ok := columns[column]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row := models.ProductImportRow{
+			Row:          i + 2,
+			SKU:          cell("sku"),
+			Name:         cell("name"),
+			CategoryName: cell("category"),
+		}
+		for _, column := range []string{"id", "category_id", "price", "cost_price", "stock", "min_stock"} {
+			value, err := parseImportInt(cell(column))
+			if err != nil {
+				rowErrors = append(rowErrors, models.ProductImportError{Row: row.Row, Column: column, Message: err.Error()})
+				continue
+			}
+			switch {
+			case column == "id":
+				if value != nil {
+					row.ID = *value
+				}
+			case column == "category_id":
+				if value != nil {
+					row.CategoryID = *value
+				}
+			default:
+				*importIntField(&row, column) = value
+			}
+		}
+
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// importIntField - field angka opsional di ProductImportRow untuk nama kolom tersebut
+func importIntField(row *models.ProductImportRow, column string) **int {
+	switch column {
+	case "price":
+		return &row.Price
+	case "cost_price":
+		return &row.CostPrice
+	case "stock":
+		return &row.Stock
+	default:
+		return &row.MinStock
+	}
+}
+
+// parseImportInt - sel kosong = nil; angka desimal dari spreadsheet (mis. "15000.0") diterima jika bulat
+func parseImportInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return &n, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return nil, fmt.Errorf("%q bukan bilangan bulat", value)
+	}
+	n := int(f)
+	return &n, nil
+}
+
+// Export - tulis seluruh katalog ke w dalam format yang sama dengan import.
+// CSV ditulis per baris langsung dari cursor database; XLSX memakai stream writer excelize.
+func (s *ProductService) Export(w io.Writer, format string) error {
+	record := func(p *models.Product) []string {
+		categoryID := ""
+		if p.CategoryID > 0 {
+			categoryID = strconv.Itoa(p.CategoryID)
+		}
+		return []string{strconv.Itoa(p.ID), p.SKU, p.Name, categoryID, p.CategoryName, strconv.Itoa(p.Price),
+			strconv.Itoa(p.CostPrice), strconv.Itoa(p.Stock), strconv.Itoa(p.MinStock)}
+	}
+
+	if format == models.ProductFileCSV {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(models.ProductFileColumns); err != nil {
+			return err
+		}
+		err := s.repo.ForEach(func(p *models.Product) error {
+			return writer.Write(record(p))
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	const sheet = "Sheet1"
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	toRow := func(values []string) []interface{} {
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		return row
+	}
+	if err := sw.SetRow("A1", toRow(models.ProductFileColumns)); err != nil {
+		return err
+	}
+
+	rowNum := 1
+	err = s.repo.ForEach(func(p *models.Product) error {
+		rowNum++
+		cell, err := excelize.CoordinatesToCellName(1, rowNum)
+		if err != nil {
+			return err
+		}
+		values := toRow(record(p))
+		// Kolom angka ditulis sebagai number supaya bisa dihitung di spreadsheet
+		values[0], values[5], values[6], values[7], values[8] = p.ID, p.Price, p.CostPrice, p.Stock, p.MinStock
+		if p.CategoryID > 0 {
+			values[3] = p.CategoryID
+		}
+		return sw.SetRow(cell, values)
+	})
+	if err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
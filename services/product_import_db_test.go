@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/testdb"
+	"strings"
+	"testing"
+)
+
+// TestProductExportImportRoundTrip - file export yang diimport ulang tanpa diubah tidak boleh membuat
+// produk baru: produk tanpa SKU dan varian dicocokkan lewat kolom id.
+func TestProductExportImportRoundTrip(t *testing.T) {
+	db := testdb.Open(t)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	service := NewProductService(repositories.NewProductRepository(db), categoryRepo, repositories.NewStockRepository(db))
+
+	category := &models.Category{Name: "Minuman"}
+	if err := categoryRepo.Create(category); err != nil {
+		t.Fatal(err)
+	}
+	parent := &models.Product{Name: "Kaos", Price: 50000}
+	products := []*models.Product{
+		{Name: "Teh Botol", Price: 5000, Stock: 12, CategoryID: category.ID},
+		{Name: "Kopi Sachet", SKU: "KOPI-1", Price: 2000, Stock: 30},
+		parent,
+	}
+	for _, p := range products {
+		if err := service.Create(p, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, size := range []string{"S", "M"} {
+		if err := service.Create(&models.Product{ParentID: parent.ID, VariantName: size, Price: 50000, Stock: 5}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []string{models.ProductFileCSV, models.ProductFileXLSX} {
+		t.Run(format, func(t *testing.T) {
+			var file bytes.Buffer
+			if err := service.Export(&file, format); err != nil {
+				t.Fatal(err)
+			}
+			result, err := service.Import(&file, format, models.ProductImportOptions{DryRun: true}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Errors) > 0 {
+				t.Fatalf("errors = %+v", result.Errors)
+			}
+			if result.TotalRows != 5 || result.Created != 0 || result.Updated != 5 {
+				t.Errorf("total %d, created %d, updated %d; want 5 baris semuanya update",
+					result.TotalRows, result.Created, result.Updated)
+			}
+		})
+	}
+
+	t.Run("id tidak dikenal", func(t *testing.T) {
+		file := strings.NewReader("id,name,price\n999999,Produk Hilang,1000\n")
+		result, err := service.Import(file, models.ProductFileCSV, models.ProductImportOptions{DryRun: true}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Errors) != 1 || result.Errors[0].Column != "id" || result.Created != 0 {
+			t.Errorf("result = %+v, want satu error di kolom id", result)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"kasir-api/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *int
+		wantErr bool
+	}{
+		{name: "kosong", value: "", want: nil},
+		{name: "bulat", value: "15000", want: intPtr(15000)},
+		{name: "negatif tetap diparse", value: "-5", want: intPtr(-5)},
+		{name: "desimal bulat dari spreadsheet", value: "15000.0", want: intPtr(15000)},
+		{name: "notasi ilmiah bulat", value: "1.5e3", want: intPtr(1500)},
+		{name: "pecahan", value: "12.5", wantErr: true},
+		{name: "pemisah ribuan", value: "15.000,00", wantErr: true},
+		{name: "teks", value: "abc", wantErr: true},
+		{name: "terlalu besar", value: "1e12", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseImportInt(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseImportInt(%q) = %v, want error", tt.value, *got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseImportInt(%q) error: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseImportInt(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseImportRows(t *testing.T) {
+	records := [][]string{
+		{"\ufeffID", "Kode", "Nama Produk", "Kategori", "Harga Jual", "HPP", "Stok", "Stok Minimum", "Catatan"},
+		{"1", " SKU-1 ", "Indomie", "Makanan", "3500", "2800.0", "40", "5", "diabaikan"},
+		{"", "", "", "", "", "", "", "", ""},
+		{"", "SKU-2", "", "", "", "", "", ""},
+		{"", "", "Teh", "", "12.5", "", "x", ""},
+		{"", "SKU-3"},
+	}
+	rows, rowErrors, err := parseImportRows(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.ProductImportRow{
+		{Row: 2, ID: 1, SKU: "SKU-1", Name: "Indomie", CategoryName: "Makanan", Price: intPtr(3500), CostPrice: intPtr(2800), Stock: intPtr(40), MinStock: intPtr(5)},
+		{Row: 4, SKU: "SKU-2"},
+		{Row: 5, Name: "Teh"},
+		{Row: 6, SKU: "SKU-3"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v\nwant %+v", rows, want)
+	}
+
+	wantErrors := []models.ProductImportError{
+		{Row: 5, Column: "price", Message: `"12.5" bukan bilangan bulat`},
+		{Row: 5, Column: "stock", Message: `"x" bukan bilangan bulat`},
+	}
+	if !reflect.DeepEqual(rowErrors, wantErrors) {
+		t.Errorf("errors = %+v, want %+v", rowErrors, wantErrors)
+	}
+}
+
+func TestParseImportRowsHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		records [][]string
+		wantErr string
+	}{
+		{name: "file kosong", records: nil, wantErr: "file kosong"},
+		{name: "tanpa name dan sku", records: [][]string{{"price", "stock"}}, wantErr: "name atau sku"},
+		{name: "alias kolom dobel", records: [][]string{{"name", "harga", "price"}}, wantErr: "kolom price"},
+		{name: "cukup sku", records: [][]string{{"sku", "stock"}}},
+		{name: "category_id", records: [][]string{{"name", "kategori_id"}, {"Kopi", "7"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseImportRows(tt.records)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("parseImportRows error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseImportRows error = %v, want mengandung %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	rows, _, err := parseImportRows([][]string{{"name", "kategori_id"}, {"Kopi", "7"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].CategoryID != 7 {
+		t.Errorf("category_id = %d, want 7", rows[0].CategoryID)
+	}
+}
+
+func TestReadProductFileCSVSeparator(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{
+			name:  "koma",
+			input: "sku,name,price\nA1,\"Kopi, Susu\",5000\n",
+			want:  [][]string{{"sku", "name", "price"}, {"A1", "Kopi, Susu", "5000"}},
+		},
+		{
+			name:  "titik koma dari Excel locale Indonesia",
+			input: "sku;name;price\r\nA1;Kopi, Susu;5000\r\n",
+			want:  [][]string{{"sku", "name", "price"}, {"A1", "Kopi, Susu", "5000"}},
+		},
+		{
+			name:  "titik koma dengan koma di header",
+			input: "sku;name;harga, rupiah;stok\nA1;Teh;3000;5\n",
+			want:  [][]string{{"sku", "name", "harga, rupiah", "stok"}, {"A1", "Teh", "3000", "5"}},
+		},
+		{
+			name:  "baris data saja yang bertitik koma tidak mengubah pemisah",
+			input: "sku,name\nA1,Teh; manis\n",
+			want:  [][]string{{"sku", "name"}, {"A1", "Teh; manis"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readProductFile(strings.NewReader(tt.input), models.ProductFileCSV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readProductFile = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
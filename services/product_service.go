@@ -2,49 +2,390 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultStockHistoryLimit = 50
+	maxStockHistoryLimit     = 200
+
+	defaultProductLimit = 50
+	maxProductLimit     = 200
+
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	minSearchQueryLen  = 2
+
+	maxVariantNameLen = 100
+	maxPatchAttempts  = 3
 )
 
 type ProductService struct {
 	repo         *repositories.ProductRepository
 	categoryRepo *repositories.CategoryRepository
+	stockRepo    *repositories.StockRepository
 }
 
-func NewProductService(repo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository) *ProductService {
-	return &ProductService{repo: repo, categoryRepo: categoryRepo}
+func NewProductService(repo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, stockRepo *repositories.StockRepository) *ProductService {
+	return &ProductService{repo: repo, categoryRepo: categoryRepo, stockRepo: stockRepo}
 }
 
-func (s *ProductService) GetAll(name string) ([]models.Product, error) {
-	return s.repo.GetAll(name)
+// GetAll - daftar produk dengan filter, sort (default nama A-Z), dan pagination
+func (s *ProductService) GetAll(filter models.ProductFilter) (*models.ProductListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultProductLimit
+	}
+	if filter.Limit > maxProductLimit {
+		filter.Limit = maxProductLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Sort == "" {
+		filter.Sort = "name"
+	}
+	if !slices.Contains([]string{"name", "price", "stock", "created_at"}, filter.Sort) {
+		return nil, errors.New("sort harus salah satu dari: name, price, stock, created_at")
+	}
+	if filter.Order == "" {
+		filter.Order = "asc"
+	}
+	if filter.Order != "asc" && filter.Order != "desc" {
+		return nil, errors.New("order harus asc atau desc")
+	}
+	if filter.MinPrice < 0 || filter.MaxPrice < 0 {
+		return nil, errors.New("min_price dan max_price tidak boleh negatif")
+	}
+	if filter.MinPrice > 0 && filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice {
+		return nil, errors.New("min_price tidak boleh lebih besar dari max_price")
+	}
+
+	products, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ProductListResponse{
+		Data:   products,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
 }
 
-func (s *ProductService) Create(data *models.Product) error {
-	// Validasi category_id jika diisi
-	if data.CategoryID > 0 {
-		_, err := s.categoryRepo.GetByID(data.CategoryID)
-		if err != nil {
-			return errors.New("category_id tidak ditemukan")
+// Search - pencarian produk untuk type-ahead, terurut relevansi
+func (s *ProductService) Search(query string, limit int) ([]models.Product, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < minSearchQueryLen {
+		return nil, fmt.Errorf("q minimal %d karakter", minSearchQueryLen)
+	}
+	if !strings.ContainsFunc(query, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return nil, errors.New("q harus mengandung huruf atau angka")
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	return s.repo.Search(query, limit)
+}
+
+// GetLowStock - produk dengan stok di bawah atau sama dengan min_stock
+func (s *ProductService) GetLowStock() ([]models.Product, error) {
+	return s.repo.GetLowStock()
+}
+
+func (s *ProductService) Create(data *models.Product, userID int) error {
+	if err := validateTaxRate(data.TaxRate); err != nil {
+		return err
+	}
+	if data.Stock < 0 {
+		return errors.New("stock tidak boleh negatif")
+	}
+	if data.MinStock < 0 {
+		return errors.New("min_stock tidak boleh negatif")
+	}
+	if data.CostPrice < 0 {
+		return errors.New("cost_price tidak boleh negatif")
+	}
+	if err := normalizeProductCodes(data); err != nil {
+		return err
+	}
+	if err := s.applyVariantParent(data); err != nil {
+		return err
+	}
+	// Kategori varian diturunkan dari induk, jadi tetap boleh walau sudah diarsipkan
+	if data.ParentID == 0 {
+		if err := s.validateCategory(data.CategoryID); err != nil {
+			return err
 		}
 	}
-	return s.repo.Create(data)
+	return s.repo.Create(data, userID)
 }
 
 func (s *ProductService) GetByID(id int) (*models.Product, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *ProductService) Update(product *models.Product) error {
-	// Validasi category_id jika diisi
-	if product.CategoryID > 0 {
-		_, err := s.categoryRepo.GetByID(product.CategoryID)
+// GetByBarcode - cari produk dari hasil scan barcode
+func (s *ProductService) GetByBarcode(code string) (*models.Product, error) {
+	normalized, err := normalizeBarcode(code)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByBarcode(normalized)
+}
+
+func (s *ProductService) Update(product *models.Product, userID int) error {
+	// Produk tidak bisa dipindah ke / dari induk lain lewat edit
+	existing, err := s.repo.GetByID(product.ID)
+	if err != nil {
+		return err
+	}
+	product.ParentID = existing.ParentID
+	// Stok tidak ikut diganti: nilai stok di body bisa basi (stok bergerak tanpa menaikkan version),
+	// perubahan stok lewat PATCH stock atau penyesuaian stok
+	return s.update(product, existing.CategoryID, false, userID)
+}
+
+// Patch - ubah sebagian field produk; field yang tidak dikirim tetap. Tanpa version dari client,
+// perubahan tetap dicek terhadap versi yang baru dibaca sehingga tidak menimpa edit lain yang
+// terjadi di antaranya (dicoba ulang beberapa kali).
+func (s *ProductService) Patch(id int, patch models.ProductPatch, userID int) (*models.Product, error) {
+	if patch.Name != nil && strings.TrimSpace(*patch.Name) == "" {
+		return nil, errors.New("name tidak boleh kosong")
+	}
+
+	for attempt := 1; ; attempt++ {
+		product, err := s.repo.GetByID(id)
 		if err != nil {
-			return errors.New("category_id tidak ditemukan")
+			return nil, err
+		}
+		if patch.Version > 0 && patch.Version != product.Version {
+			return nil, repositories.ErrVersionConflict
+		}
+		product.Variants = nil
+		currentCategoryID := product.CategoryID
+		applyProductPatch(product, patch)
+
+		err = s.update(product, currentCategoryID, patch.Stock != nil, userID)
+		if errors.Is(err, repositories.ErrVersionConflict) && patch.Version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.repo.GetByID(id)
+	}
+}
+
+// applyProductPatch - salin field yang dikirim di patch ke product
+func applyProductPatch(product *models.Product, patch models.ProductPatch) {
+	if patch.Name != nil {
+		product.Name = *patch.Name
+	}
+	if patch.VariantName != nil {
+		product.VariantName = *patch.VariantName
+	}
+	if patch.SKU != nil {
+		product.SKU = *patch.SKU
+	}
+	if patch.Barcodes != nil {
+		product.Barcodes = *patch.Barcodes
+	}
+	if patch.Price != nil {
+		product.Price = *patch.Price
+	}
+	if patch.CostPrice != nil {
+		product.CostPrice = *patch.CostPrice
+	}
+	if patch.Stock != nil {
+		product.Stock = *patch.Stock
+	}
+	if patch.MinStock != nil {
+		product.MinStock = *patch.MinStock
+	}
+	if patch.CategoryID != nil {
+		product.CategoryID = *patch.CategoryID
+	}
+	if patch.TaxRate.Set {
+		product.TaxRate = patch.TaxRate.Value
+	}
+}
+
+// update - validasi lalu simpan perubahan produk (PUT / PATCH); ParentID harus sudah diisi dari data lama.
+// currentCategoryID boleh tetap dipakai walau kategorinya sudah diarsipkan.
+func (s *ProductService) update(product *models.Product, currentCategoryID int, updateStock bool, userID int) error {
+	if err := validateTaxRate(product.TaxRate); err != nil {
+		return err
+	}
+	if updateStock && product.Stock < 0 {
+		return errors.New("stock tidak boleh negatif")
+	}
+	if product.MinStock < 0 {
+		return errors.New("min_stock tidak boleh negatif")
+	}
+	if product.CostPrice < 0 {
+		return errors.New("cost_price tidak boleh negatif")
+	}
+	if err := normalizeProductCodes(product); err != nil {
+		return err
+	}
+	if err := s.applyVariantParent(product); err != nil {
+		return err
+	}
+	if product.CategoryID != currentCategoryID {
+		if err := s.validateCategory(product.CategoryID); err != nil {
+			return err
 		}
 	}
-	return s.repo.Update(product)
+	return s.repo.Update(product, updateStock, userID)
 }
 
+// Delete - hapus permanen, hanya untuk produk yang belum pernah dipakai
 func (s *ProductService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
+
+// Archive - sembunyikan produk dari daftar, pencarian, dan checkout; riwayat & laporan tetap utuh
+func (s *ProductService) Archive(id int) error {
+	return s.repo.Archive(id)
+}
+
+func (s *ProductService) Restore(id int) error {
+	return s.repo.Restore(id)
+}
+
+// validateCategory - category_id opsional; jika diisi harus ada dan belum diarsipkan
+func (s *ProductService) validateCategory(categoryID int) error {
+	if categoryID <= 0 {
+		return nil
+	}
+	category, err := s.categoryRepo.GetByID(categoryID)
+	if err != nil {
+		return errors.New("category_id tidak ditemukan")
+	}
+	if category.DeletedAt != nil {
+		return errors.New("kategori sudah diarsipkan")
+	}
+	return nil
+}
+
+func (s *ProductService) GetStockHistory(filter models.StockMovementFilter) (*models.StockMovementListResponse, error) {
+	if _, err := s.repo.GetByID(filter.ProductID); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultStockHistoryLimit
+	}
+	if filter.Limit > maxStockHistoryLimit {
+		filter.Limit = maxStockHistoryLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	movements, total, err := s.stockRepo.GetHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StockMovementListResponse{
+		Data:   movements,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// RecordStockMovement - mutasi stok manual (adjustment/transfer) dengan alasan wajib
+func (s *ProductService) RecordStockMovement(productID int, req models.StockMovementRequest, userID int) (*models.StockMovement, error) {
+	if !slices.Contains(models.ManualStockMovementTypes, req.Type) {
+		return nil, fmt.Errorf("type harus salah satu dari: %s", strings.Join(models.ManualStockMovementTypes, ", "))
+	}
+	if req.Quantity == 0 {
+		return nil, errors.New("quantity tidak boleh 0")
+	}
+	if strings.TrimSpace(req.Note) == "" {
+		return nil, errors.New("note wajib diisi")
+	}
+
+	movement := &models.StockMovement{
+		ProductID: productID,
+		Type:      req.Type,
+		Quantity:  req.Quantity,
+		Note:      req.Note,
+		UserID:    userID,
+	}
+	if err := s.stockRepo.Record(movement); err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// applyVariantParent - untuk varian, wajibkan variant_name lalu turunkan nama dan kategori dari produk induk
+func (s *ProductService) applyVariantParent(product *models.Product) error {
+	if product.ParentID == 0 {
+		product.VariantName = ""
+		return nil
+	}
+
+	product.VariantName = strings.TrimSpace(product.VariantName)
+	if product.VariantName == "" {
+		return errors.New("variant_name wajib diisi untuk varian")
+	}
+	if len(product.VariantName) > maxVariantNameLen {
+		return fmt.Errorf("variant_name maksimal %d karakter", maxVariantNameLen)
+	}
+
+	parent, err := s.repo.GetByID(product.ParentID)
+	if errors.Is(err, repositories.ErrProductNotFound) {
+		return errors.New("parent_id tidak ditemukan")
+	}
+	if err != nil {
+		return err
+	}
+	if parent.ParentID != 0 {
+		return errors.New("varian tidak bisa punya varian")
+	}
+
+	product.Name = parent.Name + " - " + product.VariantName
+	product.CategoryID = parent.CategoryID
+	return nil
+}
+
+// normalizeProductCodes - rapikan SKU, validasi dan normalisasi barcode, buang barcode duplikat
+func normalizeProductCodes(product *models.Product) error {
+	product.SKU = strings.TrimSpace(product.SKU)
+	if len(product.SKU) > maxSKULen {
+		return fmt.Errorf("sku maksimal %d karakter", maxSKULen)
+	}
+
+	barcodes := make([]string, 0, len(product.Barcodes))
+	for _, code := range product.Barcodes {
+		normalized, err := normalizeBarcode(code)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(barcodes, normalized) {
+			barcodes = append(barcodes, normalized)
+		}
+	}
+	product.Barcodes = barcodes
+	return nil
+}
+
+// validateTaxRate - tarif pajak opsional, jika diisi harus 0-100 persen
+func validateTaxRate(rate *float64) error {
+	if rate != nil && (*rate < 0 || *rate > 100) {
+		return errors.New("tax_rate harus antara 0 dan 100")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"slices"
+	"strings"
+)
+
+type PromotionService struct {
+	repo         *repositories.PromotionRepository
+	productRepo  *repositories.ProductRepository
+	categoryRepo *repositories.CategoryRepository
+}
+
+func NewPromotionService(repo *repositories.PromotionRepository, productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository) *PromotionService {
+	return &PromotionService{repo: repo, productRepo: productRepo, categoryRepo: categoryRepo}
+}
+
+func (s *PromotionService) GetAll() ([]models.Promotion, error) {
+	return s.repo.GetAll()
+}
+
+func (s *PromotionService) Create(data *models.Promotion) error {
+	if err := s.validate(data); err != nil {
+		return err
+	}
+	return s.repo.Create(data)
+}
+
+func (s *PromotionService) GetByID(id int) (*models.Promotion, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PromotionService) Update(promotion *models.Promotion) error {
+	if err := s.validate(promotion); err != nil {
+		return err
+	}
+	return s.repo.Update(promotion)
+}
+
+func (s *PromotionService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+// validate - cek field wajib sesuai jenis promo
+func (s *PromotionService) validate(p *models.Promotion) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name wajib diisi")
+	}
+	if !slices.Contains(models.PromotionTypes, p.Type) {
+		return fmt.Errorf("type %q tidak dikenal (pilihan: %s)", p.Type, strings.Join(models.PromotionTypes, ", "))
+	}
+	if p.StartAt.IsZero() || p.EndAt.IsZero() {
+		return errors.New("start_at dan end_at wajib diisi")
+	}
+	if !p.EndAt.After(p.StartAt) {
+		return errors.New("end_at harus setelah start_at")
+	}
+
+	switch p.Type {
+	case models.PromotionTypePercentage, models.PromotionTypeCategory:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("value untuk promo persen harus 1-100")
+		}
+	case models.PromotionTypeFixed:
+		if p.Value <= 0 {
+			return errors.New("value untuk promo potongan harus lebih dari 0")
+		}
+	case models.PromotionTypeBuyXGetY:
+		if p.BuyQty <= 0 || p.GetQty <= 0 {
+			return errors.New("buy_qty dan get_qty harus lebih dari 0")
+		}
+	case models.PromotionTypeBundle:
+		if p.BundleQty <= 1 || p.BundlePrice <= 0 {
+			return errors.New("bundle_qty harus lebih dari 1 dan bundle_price lebih dari 0")
+		}
+	}
+
+	if p.Type == models.PromotionTypeCategory {
+		if p.CategoryID <= 0 {
+			return errors.New("category_id wajib diisi untuk promo kategori")
+		}
+		if _, err := s.categoryRepo.GetByID(p.CategoryID); err != nil {
+			return errors.New("category_id tidak ditemukan")
+		}
+		p.ProductID = 0
+		return nil
+	}
+
+	if p.ProductID <= 0 {
+		return errors.New("product_id wajib diisi")
+	}
+	if _, err := s.productRepo.GetByID(p.ProductID); err != nil {
+		return errors.New("product_id tidak ditemukan")
+	}
+	p.CategoryID = 0
+	return nil
+}
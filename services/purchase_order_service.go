@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"slices"
+	"strings"
+)
+
+// PurchaseOrderStatuses - status PO yang valid untuk filter
+var PurchaseOrderStatuses = []string{
+	models.PurchaseOrderStatusDraft,
+	models.PurchaseOrderStatusOrdered,
+	models.PurchaseOrderStatusPartiallyReceived,
+	models.PurchaseOrderStatusReceived,
+	models.PurchaseOrderStatusCancelled,
+}
+
+type PurchaseOrderService struct {
+	repo         *repositories.PurchaseOrderRepository
+	supplierRepo *repositories.SupplierRepository
+}
+
+func NewPurchaseOrderService(repo *repositories.PurchaseOrderRepository, supplierRepo *repositories.SupplierRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo, supplierRepo: supplierRepo}
+}
+
+func (s *PurchaseOrderService) GetAll(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	if filter.Status != "" && !slices.Contains(PurchaseOrderStatuses, filter.Status) {
+		return nil, fmt.Errorf("status harus salah satu dari: %s", strings.Join(PurchaseOrderStatuses, ", "))
+	}
+	return s.repo.GetAll(filter)
+}
+
+func (s *PurchaseOrderService) GetByID(id int) (*models.PurchaseOrder, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) Create(req models.PurchaseOrderRequest, userID int) (*models.PurchaseOrder, error) {
+	if err := s.validateRequest(req); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(req, userID)
+}
+
+// Update - hanya PO berstatus draft yang bisa diubah
+func (s *PurchaseOrderService) Update(id int, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	if err := s.validateRequest(req); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateDraft(id, req)
+}
+
+func (s *PurchaseOrderService) validateRequest(req models.PurchaseOrderRequest) error {
+	if req.SupplierID <= 0 {
+		return errors.New("supplier_id wajib diisi")
+	}
+	if _, err := s.supplierRepo.GetByID(req.SupplierID); err != nil {
+		if errors.Is(err, repositories.ErrSupplierNotFound) {
+			return errors.New("supplier_id tidak ditemukan")
+		}
+		return err
+	}
+	if len(req.Items) == 0 {
+		return errors.New("items tidak boleh kosong")
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.ProductID <= 0 {
+			return errors.New("product_id wajib diisi")
+		}
+		if seen[item.ProductID] {
+			return fmt.Errorf("product id %d muncul lebih dari sekali", item.ProductID)
+		}
+		seen[item.ProductID] = true
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity untuk product id %d harus lebih dari 0", item.ProductID)
+		}
+		if item.UnitCost < 0 {
+			return fmt.Errorf("unit_cost untuk product id %d tidak boleh negatif", item.ProductID)
+		}
+	}
+	return nil
+}
+
+// Order - kirim PO ke supplier (draft -> ordered)
+func (s *PurchaseOrderService) Order(id int) (*models.PurchaseOrder, error) {
+	if err := s.repo.MarkOrdered(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *PurchaseOrderService) Cancel(id int) (*models.PurchaseOrder, error) {
+	if err := s.repo.Cancel(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// Receive - terima barang (sebagian atau seluruh sisa pesanan) dan tambahkan ke stok
+func (s *PurchaseOrderService) Receive(id int, req models.GoodsReceiptRequest, userID int) (*models.GoodsReceipt, error) {
+	if len(req.Items) == 0 {
+		return nil, errors.New("items tidak boleh kosong")
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("product id %d muncul lebih dari sekali", item.ProductID)
+		}
+		seen[item.ProductID] = true
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity untuk product id %d harus lebih dari 0", item.ProductID)
+		}
+	}
+
+	return s.repo.Receive(id, req, userID)
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strconv"
+	"strings"
+)
+
+const defaultReceiptWidth = 32
+
+// Perintah ESC/POS dasar
+var (
+	escposInit        = []byte{0x1B, 0x40}
+	escposAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escposAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escposBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escposBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escposFeedAndCut  = []byte{0x1B, 0x64, 0x04, 0x1D, 0x56, 0x01}
+)
+
+var paymentMethodLabels = map[string]string{
+	models.PaymentMethodCash:    "Tunai",
+	models.PaymentMethodQRIS:    "QRIS",
+	models.PaymentMethodDebit:   "Debit",
+	models.PaymentMethodCredit:  "Kartu Kredit",
+	models.PaymentMethodEWallet: "E-Wallet",
+}
+
+type ReceiptService struct {
+	transactionRepo *repositories.TransactionRepository
+	store           models.StoreInfo
+}
+
+func NewReceiptService(transactionRepo *repositories.TransactionRepository, store models.StoreInfo) *ReceiptService {
+	return &ReceiptService{transactionRepo: transactionRepo, store: store}
+}
+
+// Render - render struk transaksi sesuai format; width (32/48 kolom) hanya dipakai untuk text dan escpos
+func (s *ReceiptService) Render(transactionID int, format string, width int) ([]byte, string, error) {
+	if width == 0 {
+		width = defaultReceiptWidth
+	}
+	if width != 32 && width != 48 {
+		return nil, "", errors.New("width harus 32 atau 48")
+	}
+
+	transaction, err := s.transactionRepo.GetByID(transactionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case "", models.ReceiptFormatText:
+		return []byte(s.renderText(transaction, width)), "text/plain; charset=utf-8", nil
+	case models.ReceiptFormatHTML:
+		body, err := s.renderHTML(transaction)
+		return body, "text/html; charset=utf-8", err
+	case models.ReceiptFormatESCPOS:
+		return s.renderESCPOS(transaction, width), "application/octet-stream", nil
+	default:
+		return nil, "", fmt.Errorf("format %q tidak dikenal (pilihan: text, html, escpos)", format)
+	}
+}
+
+// receiptLine - satu baris label/nilai di bagian ringkasan struk
+type receiptLine struct {
+	Label string
+	Value string
+	Bold  bool
+}
+
+func (s *ReceiptService) summaryLines(t *models.Transaction) []receiptLine {
+	lines := []receiptLine{{Label: "Subtotal", Value: formatRupiah(t.SubtotalAmount)}}
+	if t.DiscountAmount > 0 {
+		lines = append(lines, receiptLine{Label: "Diskon", Value: "-" + formatRupiah(t.DiscountAmount)})
+	}
+	if t.ServiceChargeAmount > 0 {
+		lines = append(lines, receiptLine{Label: "Service", Value: formatRupiah(t.ServiceChargeAmount)})
+	}
+	if t.TaxAmount > 0 {
+		label := "PPN"
+		if t.TaxInclusive {
+			label = "PPN (termasuk)"
+		}
+		lines = append(lines, receiptLine{Label: label, Value: formatRupiah(t.TaxAmount)})
+	}
+	lines = append(lines, receiptLine{Label: "TOTAL", Value: formatRupiah(t.TotalAmount), Bold: true})
+
+	for _, p := range t.Payments {
+		label := paymentMethodLabels[p.Method]
+		if label == "" {
+			label = p.Method
+		}
+		lines = append(lines, receiptLine{Label: label, Value: formatRupiah(p.Tendered)})
+	}
+	if t.ChangeAmount > 0 {
+		lines = append(lines, receiptLine{Label: "Kembali", Value: formatRupiah(t.ChangeAmount)})
+	}
+	for _, r := range t.Refunds {
+		lines = append(lines, receiptLine{Label: strings.ToUpper(r.Type), Value: "-" + formatRupiah(r.TotalAmount)})
+	}
+	return lines
+}
+
+// textBlock - isi struk per bagian, dipakai bersama oleh format text dan escpos
+type textBlock struct {
+	header  []string
+	body    []string
+	summary []receiptLine
+	footer  []string
+}
+
+func (s *ReceiptService) buildTextBlock(t *models.Transaction, width int) textBlock {
+	var b textBlock
+
+	for _, line := range []string{s.store.Name, s.store.Address, s.store.Phone} {
+		if line != "" {
+			b.header = append(b.header, wrapText(line, width)...)
+		}
+	}
+
+	b.body = append(b.body, strings.Repeat("-", width))
+	b.body = append(b.body, padBetween("No. #"+strconv.Itoa(t.ID), t.CreatedAt.Format("02/01/2006 15:04"), width))
+	if t.CashierName != "" {
+		b.body = append(b.body, padBetween("Kasir", t.CashierName, width))
+	}
+	if t.Status == models.TransactionStatusVoided {
+		b.body = append(b.body, centerText("*** VOID ***", width))
+	}
+	b.body = append(b.body, strings.Repeat("-", width))
+	for _, d := range t.Details {
+		b.body = append(b.body, wrapText(d.ProductName, width)...)
+		qty := fmt.Sprintf("  %d x %s", d.Quantity, formatRupiah(d.UnitPrice))
+		b.body = append(b.body, padBetween(qty, formatRupiah(d.UnitPrice*d.Quantity), width))
+		if d.DiscountAmount > 0 {
+			b.body = append(b.body, padBetween("  Diskon", "-"+formatRupiah(d.DiscountAmount), width))
+		}
+	}
+	b.body = append(b.body, strings.Repeat("-", width))
+
+	b.summary = s.summaryLines(t)
+
+	if s.store.Footer != "" {
+		b.footer = append(b.footer, strings.Repeat("-", width))
+		for _, line := range strings.Split(s.store.Footer, "\n") {
+			b.footer = append(b.footer, wrapText(line, width)...)
+		}
+	}
+
+	return b
+}
+
+func (s *ReceiptService) renderText(t *models.Transaction, width int) string {
+	b := s.buildTextBlock(t, width)
+
+	var sb strings.Builder
+	for _, line := range b.header {
+		sb.WriteString(centerText(line, width) + "\n")
+	}
+	for _, line := range b.body {
+		sb.WriteString(line + "\n")
+	}
+	for _, line := range b.summary {
+		sb.WriteString(padBetween(line.Label, line.Value, width) + "\n")
+	}
+	for _, line := range b.footer {
+		sb.WriteString(centerText(line, width) + "\n")
+	}
+	return sb.String()
+}
+
+func (s *ReceiptService) renderESCPOS(t *models.Transaction, width int) []byte {
+	b := s.buildTextBlock(t, width)
+
+	var buf bytes.Buffer
+	buf.Write(escposInit)
+
+	buf.Write(escposAlignCenter)
+	for i, line := range b.header {
+		if i == 0 {
+			buf.Write(escposBoldOn)
+		}
+		buf.WriteString(line + "\n")
+		if i == 0 {
+			buf.Write(escposBoldOff)
+		}
+	}
+
+	buf.Write(escposAlignLeft)
+	for _, line := range b.body {
+		buf.WriteString(line + "\n")
+	}
+	for _, line := range b.summary {
+		if line.Bold {
+			buf.Write(escposBoldOn)
+		}
+		buf.WriteString(padBetween(line.Label, line.Value, width) + "\n")
+		if line.Bold {
+			buf.Write(escposBoldOff)
+		}
+	}
+
+	buf.Write(escposAlignCenter)
+	for _, line := range b.footer {
+		buf.WriteString(line + "\n")
+	}
+	buf.Write(escposFeedAndCut)
+
+	return buf.Bytes()
+}
+
+var receiptHTMLTemplate = template.Must(template.New("struk").Funcs(template.FuncMap{
+	"rupiah": formatRupiah,
+	"mul":    func(a, b int) int { return a * b },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Struk #{{.Transaction.ID}}</title>
+<style>
+body { font-family: monospace; max-width: 320px; margin: 0 auto; }
+.center { text-align: center; }
+table { width: 100%; border-collapse: collapse; }
+td.num { text-align: right; }
+hr { border: none; border-top: 1px dashed #000; }
+.bold { font-weight: bold; }
+</style>
+</head>
+<body>
+<div class="center">
+{{if .Store.Name}}<div class="bold">{{.Store.Name}}</div>{{end}}
+{{if .Store.Address}}<div>{{.Store.Address}}</div>{{end}}
+{{if .Store.Phone}}<div>{{.Store.Phone}}</div>{{end}}
+</div>
+<hr>
+<table>
+<tr><td>No. #{{.Transaction.ID}}</td><td class="num">{{.Transaction.CreatedAt.Format "02/01/2006 15:04"}}</td></tr>
+{{if .Transaction.CashierName}}<tr><td>Kasir</td><td class="num">{{.Transaction.CashierName}}</td></tr>{{end}}
+</table>
+{{if .Voided}}<div class="center bold">*** VOID ***</div>{{end}}
+<hr>
+<table>
+{{range .Transaction.Details}}
+<tr><td colspan="2">{{.ProductName}}</td></tr>
+<tr><td>&nbsp;&nbsp;{{.Quantity}} x {{rupiah .UnitPrice}}</td><td class="num">{{rupiah (mul .UnitPrice .Quantity)}}</td></tr>
+{{if gt .DiscountAmount 0}}<tr><td>&nbsp;&nbsp;Diskon</td><td class="num">-{{rupiah .DiscountAmount}}</td></tr>{{end}}
+{{end}}
+</table>
+<hr>
+<table>
+{{range .Summary}}
+<tr{{if .Bold}} class="bold"{{end}}><td>{{.Label}}</td><td class="num">{{.Value}}</td></tr>
+{{end}}
+</table>
+{{if .Footer}}<hr>
+<div class="center">{{range .Footer}}<div>{{.}}</div>{{end}}</div>{{end}}
+</body>
+</html>
+`))
+
+func (s *ReceiptService) renderHTML(t *models.Transaction) ([]byte, error) {
+	data := struct {
+		Store       models.StoreInfo
+		Transaction *models.Transaction
+		Voided      bool
+		Summary     []receiptLine
+		Footer      []string
+	}{
+		Store:       s.store,
+		Transaction: t,
+		Voided:      t.Status == models.TransactionStatusVoided,
+		Summary:     s.summaryLines(t),
+	}
+	if s.store.Footer != "" {
+		data.Footer = strings.Split(s.store.Footer, "\n")
+	}
+
+	var buf bytes.Buffer
+	if err := receiptHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatRupiah - 1500000 -> "1.500.000"
+func formatRupiah(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.Itoa(amount)
+	var sb strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteRune(c)
+	}
+	return sign + sb.String()
+}
+
+// padBetween - label rata kiri dan value rata kanan dalam satu baris selebar width
+func padBetween(left, right string, width int) string {
+	space := width - len([]rune(left)) - len([]rune(right))
+	if space < 1 {
+		maxLeft := width - len([]rune(right)) - 1
+		if maxLeft < 0 {
+			maxLeft = 0
+		}
+		left = string([]rune(left)[:maxLeft])
+		space = 1
+	}
+	return left + strings.Repeat(" ", space) + right
+}
+
+func centerText(text string, width int) string {
+	pad := (width - len([]rune(text))) / 2
+	if pad <= 0 {
+		return text
+	}
+	return strings.Repeat(" ", pad) + text
+}
+
+// wrapText - pecah teks per kata supaya tidak melebihi width kolom printer
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	current := ""
+	for _, word := range words {
+		for len([]rune(word)) > width {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		if current == "" {
+			current = word
+		} else if len([]rune(current))+1+len([]rune(word)) <= width {
+			current += " " + word
+		} else {
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bytes"
+	"kasir-api/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatRupiah(t *testing.T) {
+	tests := []struct {
+		amount int
+		want   string
+	}{
+		{0, "0"},
+		{5, "5"},
+		{999, "999"},
+		{1000, "1.000"},
+		{15000, "15.000"},
+		{150000, "150.000"},
+		{1500000, "1.500.000"},
+		{-1, "-1"},
+		{-25000, "-25.000"},
+		{-1234567, "-1.234.567"},
+	}
+	for _, tt := range tests {
+		if got := formatRupiah(tt.amount); got != tt.want {
+			t.Errorf("formatRupiah(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPadBetween(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		width       int
+		want        string
+	}{
+		{name: "32 kolom", left: "TOTAL", right: "25.000", width: 32, want: "TOTAL                     25.000"},
+		{name: "48 kolom", left: "Kembali", right: "0", width: 48, want: "Kembali" + strings.Repeat(" ", 40) + "0"},
+		{name: "nilai negatif refund", left: "REFUND", right: "-11.100", width: 32, want: "REFUND                   -11.100"},
+		{name: "pas selebar kolom", left: strings.Repeat("a", 26), right: "12.000", width: 33, want: strings.Repeat("a", 26) + " 12.000"},
+		{name: "label kepanjangan dipotong", left: strings.Repeat("a", 40), right: "12.000", width: 32, want: strings.Repeat("a", 25) + " 12.000"},
+		{name: "huruf multi-byte dihitung per karakter", left: "Kasir", right: "Zoë", width: 12, want: "Kasir    Zoë"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := padBetween(tt.left, tt.right, tt.width)
+			if got != tt.want {
+				t.Errorf("padBetween(%q, %q, %d) = %q, want %q", tt.left, tt.right, tt.width, got, tt.want)
+			}
+			if n := len([]rune(got)); n != tt.width {
+				t.Errorf("lebar %d, want %d", n, tt.width)
+			}
+		})
+	}
+}
+
+func TestCenterText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"*** VOID ***", 32, strings.Repeat(" ", 10) + "*** VOID ***"},
+		{"Toko", 48, strings.Repeat(" ", 22) + "Toko"},
+		{strings.Repeat("x", 40), 32, strings.Repeat("x", 40)},
+	}
+	for _, tt := range tests {
+		if got := centerText(tt.text, tt.width); got != tt.want {
+			t.Errorf("centerText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "muat satu baris", text: "Indomie Goreng", width: 32, want: []string{"Indomie Goreng"}},
+		{name: "kosong", text: "   ", width: 32, want: nil},
+		{
+			name:  "nama produk panjang 32 kolom",
+			text:  "Susu UHT Full Cream Rasa Coklat Kemasan 1 Liter",
+			width: 32,
+			want:  []string{"Susu UHT Full Cream Rasa Coklat", "Kemasan 1 Liter"},
+		},
+		{
+			name:  "nama produk panjang 48 kolom",
+			text:  "Susu UHT Full Cream Rasa Coklat Kemasan 1 Liter Isi 12",
+			width: 48,
+			want:  []string{"Susu UHT Full Cream Rasa Coklat Kemasan 1 Liter", "Isi 12"},
+		},
+		{
+			name:  "kata lebih panjang dari kolom dipotong",
+			text:  "Kode ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 selesai",
+			width: 32,
+			want:  []string{"Kode", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345", "6789 selesai"},
+		},
+		{name: "spasi berlebih dirapikan", text: "  Teh   Manis  ", width: 32, want: []string{"Teh Manis"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.width)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+			for _, line := range got {
+				if len([]rune(line)) > tt.width {
+					t.Errorf("baris %q melebihi %d kolom", line, tt.width)
+				}
+			}
+		})
+	}
+}
+
+// receiptTestData - transaksi dengan nama produk panjang, diskon, pajak, kembalian dan refund
+func receiptTestData() (*ReceiptService, *models.Transaction) {
+	s := &ReceiptService{store: models.StoreInfo{
+		Name:    "Toko Maju Jaya",
+		Address: "Jl. Merdeka No. 17, Kelurahan Sukamaju, Kecamatan Sukajadi",
+		Footer:  "Terima kasih\nBarang yang sudah dibeli tidak dapat ditukar",
+	}}
+	trx := &models.Transaction{
+		ID:             42,
+		SubtotalAmount: 37000,
+		DiscountAmount: 2000,
+		TaxAmount:      3850,
+		TotalAmount:    38850,
+		ChangeAmount:   11150,
+		Status:         models.TransactionStatusPartiallyRefunded,
+		CashierName:    "Siti",
+		CreatedAt:      time.Date(2026, 3, 1, 14, 5, 0, 0, time.UTC),
+		Details: []models.TransactionDetail{
+			{ProductName: "Susu UHT Full Cream Rasa Coklat Kemasan 1 Liter", Quantity: 2, UnitPrice: 18500, DiscountAmount: 2000},
+		},
+		Payments: []models.Payment{{Method: models.PaymentMethodCash, Amount: 38850, Tendered: 50000, ChangeAmount: 11150}},
+		Refunds:  []models.Refund{{Type: models.RefundTypeRefund, TotalAmount: 19425}},
+	}
+	return s, trx
+}
+
+func TestRenderTextWidth(t *testing.T) {
+	s, trx := receiptTestData()
+	for _, width := range []int{32, 48} {
+		out := s.renderText(trx, width)
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		for _, line := range lines {
+			if n := len([]rune(line)); n > width {
+				t.Errorf("width %d: baris %q selebar %d", width, line, n)
+			}
+		}
+		for _, want := range []string{
+			padBetween("  2 x 18.500", "37.000", width),
+			padBetween("  Diskon", "-2.000", width),
+			padBetween("TOTAL", "38.850", width),
+			padBetween("Tunai", "50.000", width),
+			padBetween("Kembali", "11.150", width),
+			padBetween("REFUND", "-19.425", width),
+			strings.Repeat("-", width),
+		} {
+			if !strings.Contains(out, want+"\n") {
+				t.Errorf("width %d: baris %q tidak ada di struk:\n%s", width, want, out)
+			}
+		}
+	}
+}
+
+func TestRenderTextVoidAndZeroAmounts(t *testing.T) {
+	s, trx := receiptTestData()
+	trx.Status = models.TransactionStatusVoided
+	trx.DiscountAmount, trx.TaxAmount, trx.ChangeAmount = 0, 0, 0
+	trx.Refunds = []models.Refund{{Type: models.RefundTypeVoid, TotalAmount: 0}}
+
+	out := s.renderText(trx, 32)
+	if !strings.Contains(out, centerText("*** VOID ***", 32)+"\n") {
+		t.Errorf("penanda VOID tidak ada:\n%s", out)
+	}
+	if !strings.Contains(out, padBetween("VOID", "-0", 32)+"\n") {
+		t.Errorf("baris void bernilai 0 tidak ada:\n%s", out)
+	}
+	for _, label := range []string{"Diskon    ", "PPN", "Kembali"} {
+		if strings.Contains(out, "\n"+label) {
+			t.Errorf("baris %q bernilai 0 seharusnya tidak dicetak:\n%s", label, out)
+		}
+	}
+}
+
+func TestRenderESCPOS(t *testing.T) {
+	s, trx := receiptTestData()
+	out := s.renderESCPOS(trx, 48)
+	if !bytes.HasPrefix(out, escposInit) {
+		t.Errorf("struk escpos tidak diawali perintah init")
+	}
+	if !bytes.HasSuffix(out, escposFeedAndCut) {
+		t.Errorf("struk escpos tidak diakhiri feed dan cut")
+	}
+	total := []byte(padBetween("TOTAL", "38.850", 48) + "\n")
+	bold := append(append(append([]byte{}, escposBoldOn...), total...), escposBoldOff...)
+	if !bytes.Contains(out, bold) {
+		t.Errorf("baris TOTAL tidak dicetak tebal")
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	s, trx := receiptTestData()
+	trx.Details[0].ProductName = "Kopi <Spesial> & Gula"
+	out, err := s.renderHTML(trx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+	for _, want := range []string{"Kopi &lt;Spesial&gt; &amp; Gula", "2 x 18.500", "-2.000", "<td>REFUND</td><td class=\"num\">-19.425</td>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html tidak berisi %q", want)
+		}
+	}
+	if strings.Contains(html, "*** VOID ***") {
+		t.Errorf("transaksi yang tidak di-void ditandai VOID")
+	}
+}
@@ -20,3 +20,19 @@ func (s *ReportService) GetDailySalesReport() (*models.DailySalesReport, error)
 func (s *ReportService) GetSalesReportByDateRange(startDate, endDate string) (*models.DailySalesReport, error) {
 	return s.repo.GetSalesReportByDateRange(startDate, endDate)
 }
+
+func (s *ReportService) GetTaxReport(startDate, endDate string) (*models.TaxReport, error) {
+	return s.repo.GetTaxReport(startDate, endDate)
+}
+
+func (s *ReportService) GetSalesByCashier(startDate, endDate string) (*models.CashierSalesReport, error) {
+	summaries, err := s.repo.GetSalesByCashier(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CashierSalesReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		PerKasir:  summaries,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"strings"
+)
+
+type ShiftService struct {
+	repo       *repositories.ShiftRepository
+	reportRepo *repositories.ReportRepository
+}
+
+func NewShiftService(repo *repositories.ShiftRepository, reportRepo *repositories.ReportRepository) *ShiftService {
+	return &ShiftService{repo: repo, reportRepo: reportRepo}
+}
+
+func (s *ShiftService) Open(actor models.Actor, req models.OpenShiftRequest) (*models.Shift, error) {
+	if req.OpeningFloat < 0 {
+		return nil, errors.New("opening_float tidak boleh negatif")
+	}
+	if len(actor.TerminalID) > maxTerminalIDLen {
+		return nil, fmt.Errorf("X-Terminal-ID maksimal %d karakter", maxTerminalIDLen)
+	}
+	return s.repo.Open(actor.UserID, actor.TerminalID, req.OpeningFloat)
+}
+
+// Current - Z-report berjalan untuk shift user yang sedang terbuka
+func (s *ShiftService) Current(userID int) (*models.ShiftReport, error) {
+	shift, err := s.repo.GetOpenByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildReport(shift)
+}
+
+func (s *ShiftService) AddCashMovement(userID int, req models.CashMovementRequest) (*models.CashMovement, error) {
+	if req.Type != models.CashMovementPayIn && req.Type != models.CashMovementPayout {
+		return nil, fmt.Errorf("type harus %s atau %s", models.CashMovementPayIn, models.CashMovementPayout)
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount harus lebih dari 0")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("reason wajib diisi")
+	}
+	return s.repo.AddCashMovement(userID, req.Type, req.Amount, req.Reason)
+}
+
+// Close - tutup shift user dan kembalikan Z-report final
+func (s *ShiftService) Close(userID int, req models.CloseShiftRequest) (*models.ShiftReport, error) {
+	if req.CountedCash < 0 {
+		return nil, errors.New("counted_cash tidak boleh negatif")
+	}
+	shift, err := s.repo.Close(userID, req.CountedCash, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildReport(shift)
+}
+
+func (s *ShiftService) GetAll(filter models.ShiftFilter) ([]models.Shift, error) {
+	if filter.Status != "" && filter.Status != models.ShiftStatusOpen && filter.Status != models.ShiftStatusClosed {
+		return nil, fmt.Errorf("status harus %s atau %s", models.ShiftStatusOpen, models.ShiftStatusClosed)
+	}
+	return s.repo.GetAll(filter)
+}
+
+func (s *ShiftService) GetReport(id int) (*models.ShiftReport, error) {
+	shift, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildReport(shift)
+}
+
+func (s *ShiftService) buildReport(shift *models.Shift) (*models.ShiftReport, error) {
+	report, err := s.reportRepo.GetShiftReport(shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	report.Shift = shift
+	report.KasDihitung = shift.CountedCash
+	report.Selisih = shift.OverShort
+	return report, nil
+}
@@ -1,18 +1,292 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTransactionLimit = 20
+	maxTransactionLimit     = 100
+
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+	maxTerminalIDLen      = 100
+)
+
+var (
+	// ErrIdempotencyConflict - Idempotency-Key dipakai ulang dengan payload checkout yang berbeda
+	ErrIdempotencyConflict = errors.New("Idempotency-Key sudah dipakai untuk payload checkout yang berbeda")
+	// ErrInvalidTransactionFilter - kombinasi filter riwayat transaksi tidak masuk akal
+	ErrInvalidTransactionFilter = errors.New("filter transaksi tidak valid")
 )
 
 type TransactionService struct {
-	repo *repositories.TransactionRepository
+	repo           *repositories.TransactionRepository
+	promotionRepo  *repositories.PromotionRepository
+	productRepo    *repositories.ProductRepository
+	idempotencyTTL time.Duration
+	tax            models.TaxSettings
+}
+
+func NewTransactionService(repo *repositories.TransactionRepository, promotionRepo *repositories.PromotionRepository, productRepo *repositories.ProductRepository, idempotencyTTL time.Duration, tax models.TaxSettings) *TransactionService {
+	if idempotencyTTL <= 0 {
+		idempotencyTTL = defaultIdempotencyTTL
+	}
+	return &TransactionService{repo: repo, promotionRepo: promotionRepo, productRepo: productRepo, idempotencyTTL: idempotencyTTL, tax: tax}
+}
+
+// Checkout - buat transaksi baru atas nama actor (kasir yang login). Jika idempotencyKey diisi
+// dan masih dalam retention window, response transaksi asli dikembalikan tanpa mengubah stok (replayed = true).
+// Key berlaku per kasir, sehingga key yang sama dari kasir lain tidak pernah me-replay transaksi ini.
+func (s *TransactionService) Checkout(req models.CheckoutRequest, idempotencyKey string, actor models.Actor) (transaction *models.Transaction, replayed bool, err error) {
+	if len(actor.TerminalID) > maxTerminalIDLen {
+		return nil, false, fmt.Errorf("X-Terminal-ID maksimal %d karakter", maxTerminalIDLen)
+	}
+
+	items, err := s.resolveBarcodes(req.Items)
+	if err != nil {
+		return nil, false, err
+	}
+	merged, err := mergeCheckoutItems(items)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validatePayments(req.Payments); err != nil {
+		return nil, false, err
+	}
+
+	promotions, err := s.promotionRepo.GetActive(time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+
+	input := models.NewTransaction{
+		Items:      merged,
+		Payments:   req.Payments,
+		Promotions: promotions,
+		Tax:        s.tax,
+		Actor:      actor,
+	}
+
+	if idempotencyKey == "" {
+		transaction, err = s.repo.CreateTransaction(input)
+		return transaction, false, err
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLen {
+		return nil, false, fmt.Errorf("Idempotency-Key maksimal %d karakter", maxIdempotencyKeyLen)
+	}
+
+	requestHash, err := hashCheckoutRequest(merged, req.Payments)
+	if err != nil {
+		return nil, false, err
+	}
+
+	expiredBefore := time.Now().Add(-s.idempotencyTTL)
+	if err := s.repo.DeleteExpiredIdempotencyKeys(expiredBefore); err != nil {
+		return nil, false, err
+	}
+
+	transaction, err = s.replayIdempotentCheckout(actor.UserID, idempotencyKey, requestHash, expiredBefore)
+	if err != nil || transaction != nil {
+		return transaction, transaction != nil, err
+	}
+
+	input.Idempotency = &models.IdempotencyRecord{
+		Key:         idempotencyKey,
+		CashierID:   actor.UserID,
+		RequestHash: requestHash,
+	}
+	transaction, err = s.repo.CreateTransaction(input)
+	if errors.Is(err, repositories.ErrIdempotencyKeyExists) {
+		// Retry paralel dengan key sama menang duluan; kembalikan hasil checkout tersebut
+		transaction, err = s.replayIdempotentCheckout(actor.UserID, idempotencyKey, requestHash, expiredBefore)
+		if err == nil && transaction == nil {
+			err = ErrIdempotencyConflict
+		}
+		return transaction, transaction != nil, err
+	}
+
+	return transaction, false, err
+}
+
+// resolveBarcodes - ganti item yang memakai barcode dengan product_id-nya, supaya item yang di-scan
+// dan yang dipilih manual untuk produk yang sama digabung jadi satu baris
+func (s *TransactionService) resolveBarcodes(items []models.CheckoutItem) ([]models.CheckoutItem, error) {
+	codes := make([]string, 0)
+	resolved := make([]models.CheckoutItem, len(items))
+	for i, item := range items {
+		if item.Barcode == "" {
+			resolved[i] = item
+			continue
+		}
+		if item.ProductID != 0 {
+			return nil, fmt.Errorf("isi product_id atau barcode saja, tidak keduanya (barcode %s)", item.Barcode)
+		}
+		code, err := normalizeBarcode(item.Barcode)
+		if err != nil {
+			return nil, err
+		}
+		item.Barcode = code
+		resolved[i] = item
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return resolved, nil
+	}
+
+	ids, err := s.productRepo.GetIDsByBarcodes(codes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resolved {
+		if resolved[i].Barcode == "" {
+			continue
+		}
+		id, ok := ids[resolved[i].Barcode]
+		if !ok {
+			return nil, fmt.Errorf("barcode %s tidak terdaftar", resolved[i].Barcode)
+		}
+		resolved[i].ProductID = id
+		resolved[i].Barcode = ""
+	}
+	return resolved, nil
+}
+
+// validatePayments - cek metode dan nominal; kecukupan bayar dicek setelah total dihitung di repository
+func validatePayments(payments []models.PaymentInput) error {
+	for _, p := range payments {
+		if !slices.Contains(models.PaymentMethods, p.Method) {
+			return fmt.Errorf("metode pembayaran %q tidak dikenal (pilihan: %s)", p.Method, strings.Join(models.PaymentMethods, ", "))
+		}
+		if p.Amount <= 0 {
+			return fmt.Errorf("nominal pembayaran %s harus lebih dari 0", p.Method)
+		}
+	}
+	return nil
+}
+
+// replayIdempotentCheckout - kembalikan response tersimpan untuk key milik kasir cashierID, nil jika
+// kasir tersebut belum pernah memakai key ini
+func (s *TransactionService) replayIdempotentCheckout(cashierID int, key, requestHash string, since time.Time) (*models.Transaction, error) {
+	rec, err := s.repo.GetIdempotencyRecord(cashierID, key, since)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	if rec.RequestHash != requestHash || rec.Response == nil {
+		return nil, ErrIdempotencyConflict
+	}
+
+	var transaction models.Transaction
+	if err := json.Unmarshal(rec.Response, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// hashCheckoutRequest - sha256 dari item (sudah di-merge, diurutkan by product_id) dan pembayaran,
+// supaya urutan item di payload tidak dianggap payload berbeda
+func hashCheckoutRequest(items []models.CheckoutItem, payments []models.PaymentInput) (string, error) {
+	sorted := make([]models.CheckoutItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+
+	payload, err := json.Marshal(models.CheckoutRequest{Items: sorted, Payments: payments})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// mergeCheckoutItems - gabungkan product_id yang sama dalam satu keranjang, urutan item pertama dipertahankan
+func mergeCheckoutItems(items []models.CheckoutItem) ([]models.CheckoutItem, error) {
+	merged := make([]models.CheckoutItem, 0, len(items))
+	index := make(map[int]int)
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity untuk product id %d harus lebih dari 0", item.ProductID)
+		}
+		if item.UnitPrice != nil && *item.UnitPrice < 0 {
+			return nil, fmt.Errorf("unit_price untuk product id %d tidak boleh negatif", item.ProductID)
+		}
+		if i, ok := index[item.ProductID]; ok {
+			if !samePrice(merged[i].UnitPrice, item.UnitPrice) {
+				return nil, fmt.Errorf("product id %d muncul dengan harga berbeda dalam satu keranjang", item.ProductID)
+			}
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged, nil
+}
+
+func samePrice(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *TransactionService) GetAll(filter models.TransactionFilter) (*models.TransactionListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTransactionLimit
+	}
+	if filter.Limit > maxTransactionLimit {
+		filter.Limit = maxTransactionLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.MinAmount > 0 && filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return nil, fmt.Errorf("%w: min_amount tidak boleh lebih besar dari max_amount", ErrInvalidTransactionFilter)
+	}
+	if filter.StartDate != "" && filter.EndDate != "" && filter.StartDate > filter.EndDate {
+		return nil, fmt.Errorf("%w: start_date tidak boleh setelah end_date", ErrInvalidTransactionFilter)
+	}
+
+	transactions, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransactionListResponse{
+		Data:   transactions,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+func (s *TransactionService) GetByID(id int) (*models.Transaction, error) {
+	return s.repo.GetByID(id)
 }
 
-func NewTransactionService(repo *repositories.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+func (s *TransactionService) Void(id int, reason string, actor models.Actor) (*models.Refund, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("%w: reason wajib diisi", repositories.ErrInvalidRefund)
+	}
+	return s.repo.CreateRefund(id, models.RefundTypeVoid, reason, nil, actor)
 }
 
-func (s *TransactionService) Checkout(items []models.CheckoutItem) (*models.Transaction, error) {
-	return s.repo.CreateTransaction(items)
+func (s *TransactionService) Refund(id int, req models.RefundRequest, actor models.Actor) (*models.Refund, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, fmt.Errorf("%w: reason wajib diisi", repositories.ErrInvalidRefund)
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: items tidak boleh kosong", repositories.ErrInvalidRefund)
+	}
+	return s.repo.CreateRefund(id, models.RefundTypeRefund, req.Reason, req.Items, actor)
 }
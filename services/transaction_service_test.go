@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"kasir-api/models"
+	"reflect"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestMergeCheckoutItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []models.CheckoutItem
+		want    []models.CheckoutItem
+		wantErr bool
+	}{
+		{
+			name: "baris dobel digabung, urutan kemunculan pertama dipertahankan",
+			items: []models.CheckoutItem{
+				{ProductID: 2, Quantity: 1},
+				{ProductID: 1, Quantity: 3},
+				{ProductID: 2, Quantity: 4},
+			},
+			want: []models.CheckoutItem{
+				{ProductID: 2, Quantity: 5},
+				{ProductID: 1, Quantity: 3},
+			},
+		},
+		{
+			name: "harga manual sama tetap dipakai setelah digabung",
+			items: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(7500)},
+				{ProductID: 1, Quantity: 2, UnitPrice: intPtr(7500)},
+			},
+			want: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 3, UnitPrice: intPtr(7500)},
+			},
+		},
+		{
+			name: "harga manual nol (gratis) tetap dipakai",
+			items: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(0)},
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(0)},
+			},
+			want: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 2, UnitPrice: intPtr(0)},
+			},
+		},
+		{
+			name: "harga manual berbeda ditolak",
+			items: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(7500)},
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(8000)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "harga manual dicampur harga normal ditolak",
+			items: []models.CheckoutItem{
+				{ProductID: 1, Quantity: 1},
+				{ProductID: 1, Quantity: 1, UnitPrice: intPtr(7500)},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "quantity nol ditolak",
+			items:   []models.CheckoutItem{{ProductID: 1, Quantity: 0}},
+			wantErr: true,
+		},
+		{
+			name:    "harga manual negatif ditolak",
+			items:   []models.CheckoutItem{{ProductID: 1, Quantity: 1, UnitPrice: intPtr(-1)}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeCheckoutItems(tt.items)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("mergeCheckoutItems() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeCheckoutItems() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCheckoutItems() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkoutHash - hash payload JSON melalui jalur yang sama dengan Checkout: decode, merge, lalu hash
+func checkoutHash(t *testing.T, payload string) string {
+	t.Helper()
+	var req models.CheckoutRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := mergeCheckoutItems(req.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashCheckoutRequest(merged, req.Payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashCheckoutRequest(t *testing.T) {
+	base := `{"items":[{"product_id":1,"quantity":2},{"product_id":2,"quantity":1,"unit_price":5000}],
+		"payments":[{"method":"cash","amount":20000}]}`
+
+	tests := []struct {
+		name    string
+		payload string
+		same    bool
+	}{
+		{
+			name: "urutan key dan item berbeda",
+			payload: `{"payments":[{"amount":20000,"method":"cash"}],
+				"items":[{"unit_price":5000,"quantity":1,"product_id":2},{"quantity":2,"product_id":1}]}`,
+			same: true,
+		},
+		{
+			name: "item yang sama dipecah jadi dua baris",
+			payload: `{"items":[{"product_id":1,"quantity":1},{"product_id":2,"quantity":1,"unit_price":5000},{"product_id":1,"quantity":1}],
+				"payments":[{"method":"cash","amount":20000}]}`,
+			same: true,
+		},
+		{
+			name: "quantity berbeda",
+			payload: `{"items":[{"product_id":1,"quantity":3},{"product_id":2,"quantity":1,"unit_price":5000}],
+				"payments":[{"method":"cash","amount":20000}]}`,
+		},
+		{
+			name: "harga manual berbeda",
+			payload: `{"items":[{"product_id":1,"quantity":2},{"product_id":2,"quantity":1,"unit_price":4000}],
+				"payments":[{"method":"cash","amount":20000}]}`,
+		},
+		{
+			name: "pembayaran berbeda",
+			payload: `{"items":[{"product_id":1,"quantity":2},{"product_id":2,"quantity":1,"unit_price":5000}],
+				"payments":[{"method":"qris","amount":20000}]}`,
+		},
+	}
+	want := checkoutHash(t, base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkoutHash(t, tt.payload)
+			if (got == want) != tt.same {
+				t.Errorf("hash sama = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+}
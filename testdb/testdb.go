@@ -0,0 +1,80 @@
+// Package testdb - database Postgres untuk test integrasi repositories dan services
+package testdb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Env - connection string Postgres untuk test integrasi; test yang butuh database di-skip jika kosong
+const Env = "KASIR_TEST_DB_CONN"
+
+// Open - buka koneksi ke schema baru yang sudah dimigrasi penuh (migrations/*.sql berurutan).
+// Schema dibuang setelah test selesai. Pakai database khusus test: beberapa migrasi mengecek nama
+// constraint di pg_constraint tanpa schema, sehingga constraint yang sudah ada di public tidak dibuat ulang.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+	conn := os.Getenv(Env)
+	if conn == "" {
+		t.Skipf("%s tidak diisi, test integrasi database di-skip", Env)
+	}
+
+	admin, err := sql.Open("postgres", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("kasir_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	// public tetap di search_path supaya extension yang sudah terpasang (pg_trgm) bisa dipakai
+	cfg, err := pq.NewConfig(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Runtime == nil {
+		cfg.Runtime = make(map[string]string)
+	}
+	cfg.Runtime["search_path"] = schema + ", public"
+	connector, err := pq.NewConnectorConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(25)
+	t.Cleanup(func() { db.Close() })
+
+	// Folder migrations dicari relatif ke file ini, bukan ke package yang menjalankan test
+	_, self, _, _ := runtime.Caller(0)
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(self), "..", "migrations", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("migrasi %s: %v", filepath.Base(file), err)
+		}
+	}
+	return db
+}